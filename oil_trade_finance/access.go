@@ -0,0 +1,155 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+// Role-based access control
+//
+// The roles of the submitter are read from the role attribute of its
+// certificate, a comma separated list when it has several. Before Init or
+// Invoke run a function, the policy table is consulted for the
+// roles allowed to call it. A function missing from the table cannot be
+// called by anyone, so that a new function is not left open by mistake.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// ROLEATTRIBUTE is the certificate attribute that carries the roles of the submitter
+const ROLEATTRIBUTE string = "role"
+
+// ADMINROLE is the certificate role allowed to administer the contract
+const ADMINROLE string = "admin"
+
+// CARRIERROLE is the certificate role of the carriers that move assets
+const CARRIERROLE string = "carrier"
+
+// INSPECTORROLE is the certificate role allowed to clear latched alerts
+const INSPECTORROLE string = "inspector"
+
+// BANKROLE is the certificate role of the banks financing the trade
+const BANKROLE string = "bank"
+
+// BUYERROLE is the certificate role of the buyer in the trade
+const BUYERROLE string = "buyer"
+
+// SELLERROLE is the certificate role of the seller in the trade
+const SELLERROLE string = "seller"
+
+// DEVICEROLE is the certificate role of the devices and gateways that report readings
+const DEVICEROLE string = "device"
+
+// allRoles may read everything the contract records and preview updates
+var allRoles = []string{ADMINROLE, CARRIERROLE, INSPECTORROLE, BANKROLE, BUYERROLE, SELLERROLE, DEVICEROLE}
+
+// accessPolicy lists the roles allowed to call each function, init stands
+// for Init
+var accessPolicy = map[string][]string{
+	// deploy
+	"init": {ADMINROLE},
+	// invoke
+	"createAsset":       {ADMINROLE, CARRIERROLE, SELLERROLE},
+	"updateAsset":       {ADMINROLE, CARRIERROLE, DEVICEROLE},
+	"updateAssets":      {ADMINROLE, CARRIERROLE, DEVICEROLE},
+	"patchAsset":        {ADMINROLE, CARRIERROLE, DEVICEROLE},
+	"deleteAsset":       {ADMINROLE, CARRIERROLE},
+	"restoreAsset":      {ADMINROLE, CARRIERROLE},
+	"purgeAsset":        {ADMINROLE},
+	"suppressAlerts":    {ADMINROLE, INSPECTORROLE},
+	"proposeHandoff":    {CARRIERROLE},
+	"acceptHandoff":     {CARRIERROLE},
+	"attachAsset":       {ADMINROLE, CARRIERROLE},
+	"detachAsset":       {ADMINROLE, CARRIERROLE},
+	"clearLatchedAlert": {INSPECTORROLE},
+	"registerDevice":    {ADMINROLE, CARRIERROLE},
+	"deregisterDevice":  {ADMINROLE, CARRIERROLE},
+	"pauseContract":     {ADMINROLE},
+	"resumeContract":    {ADMINROLE},
+	"retireContract":    {ADMINROLE},
+	"migrateAll":        {ADMINROLE},
+	// query
+	"readAsset":                  allRoles,
+	"readAssetDevices":           allRoles,
+	"readAssetTree":              allRoles,
+	"readAssetTypes":             allRoles,
+	"readAssetSamples":           allRoles,
+	"readAssetSchemas":           allRoles,
+	"readContractState":          allRoles,
+	"readTradeState":             allRoles,
+	"readCustodyChain":           allRoles,
+	"readDeletedAssets":          allRoles,
+	"readAlertSuppressions":      allRoles,
+	"readLatchedAlertClearances": allRoles,
+	"previewUpdate":              allRoles,
+}
+
+/*********************************  internal: access control ****************************/
+
+// checkAccess returns a permission denied error unless the submitter has one
+// of the roles allowed to call the function
+func checkAccess(stub shim.ChaincodeStubInterface, function string) error {
+	allowed, found := accessPolicy[function]
+	if !found {
+		return errors.New("Permission denied: " + function + " is not open to any role")
+	}
+	roles, err := callerRoles(stub)
+	if err != nil {
+		return err
+	}
+	for _, role := range roles {
+		for _, a := range allowed {
+			if role == a {
+				return nil
+			}
+		}
+	}
+	if len(allowed) == 1 {
+		return errors.New("Permission denied: " + function + " requires the " + allowed[0] + " role")
+	}
+	return errors.New("Permission denied: " + function + " requires one of the roles " + strings.Join(allowed, ", "))
+}
+
+// callerRoles returns the roles in the submitter's certificate
+func callerRoles(stub shim.ChaincodeStubInterface) ([]string, error) {
+	var roles []string
+
+	roleAttribute, _, err := cid.GetAttributeValue(stub, ROLEATTRIBUTE)
+	if err != nil {
+		return nil, errors.New("Permission denied: unable to read role from caller certificate: " + fmt.Sprint(err))
+	}
+	for _, role := range strings.Split(roleAttribute, ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			roles = append(roles, role)
+		}
+	}
+	if len(roles) == 0 {
+		return nil, errors.New("Permission denied: the caller certificate carries no role")
+	}
+	return roles, nil
+}
+
+// callerHasRole returns true when the submitter's certificate carries the role
+func callerHasRole(stub shim.ChaincodeStubInterface, role string) (bool, error) {
+	roles, err := callerRoles(stub)
+	if err != nil {
+		return false, err
+	}
+	for _, r := range roles {
+		if r == role {
+			return true, nil
+		}
+	}
+	return false, nil
+}
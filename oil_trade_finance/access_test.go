@@ -0,0 +1,118 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestAccessPolicy(t *testing.T) {
+	tests := []struct {
+		function string
+		roles    string
+		args     []string
+		wantErr  string
+	}{
+		{"createAsset", CARRIERROLE, []string{`{"assetID":"V2","assetType":"Vessel"}`}, ""},
+		{"createAsset", SELLERROLE, []string{`{"assetID":"V2","assetType":"Vessel"}`}, ""},
+		{"createAsset", DEVICEROLE, []string{`{"assetID":"V2","assetType":"Vessel"}`}, "Permission denied: createAsset requires one of the roles admin, carrier, seller"},
+		{"createAsset", "bank, buyer", []string{`{"assetID":"V2","assetType":"Vessel"}`}, "Permission denied"},
+		{"createAsset", "buyer,seller", []string{`{"assetID":"V2","assetType":"Vessel"}`}, ""},
+		{"purgeAsset", CARRIERROLE, []string{`{"assetID":"V1"}`}, "Permission denied: purgeAsset requires the admin role"},
+		{"clearLatchedAlert", ADMINROLE, []string{`{"assetID":"V1","alert":"SEALTAMPER","reason":"r"}`}, "Permission denied: clearLatchedAlert requires the inspector role"},
+		{"pauseContract", INSPECTORROLE, []string{`{"reason":"r"}`}, "Permission denied: pauseContract requires the admin role"},
+		{"migrateAll", CARRIERROLE, []string{`{}`}, "Permission denied: migrateAll requires the admin role"},
+		{"readAsset", BANKROLE, []string{`{"assetID":"V1"}`}, ""},
+		{"readAsset", "", []string{`{"assetID":"V1"}`}, "Permission denied: the caller certificate carries no role"},
+		{"readAsset", "auditor", []string{`{"assetID":"V1"}`}, "Permission denied: readAsset requires one of the roles"},
+		{"transferAsset", ADMINROLE, []string{`{"assetID":"V1"}`}, "Permission denied: transferAsset is not open to any role"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.function+" as "+tt.roles, func(t *testing.T) {
+			m := newTestContract(t)
+			m.mustInvoke("createAsset", `{"assetID":"V1","assetType":"Vessel"}`)
+			_, err := m.as("caller", tt.roles).invoke(tt.function, tt.args...)
+			wantError(t, err, tt.wantErr)
+		})
+	}
+}
+
+// updates create the assets they do not find, but not for the roles that
+// may not create assets
+func TestUpdatesCreateOnlyWithCreateAccess(t *testing.T) {
+	var results []BatchResult
+
+	m := newTestContract(t)
+	m.as("gauge", DEVICEROLE)
+	_, err := m.invoke("updateAsset", `{"assetID":"T9","assetType":"CrudeTank","carrier":"acme"}`)
+	wantError(t, err, "Permission denied: createAsset requires one of the roles admin, carrier, seller")
+	err = json.Unmarshal(m.mustInvoke("updateAssets", `[{"assetID":"T9","assetType":"CrudeTank","carrier":"acme"}]`), &results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Success || !strings.Contains(results[0].Error, "Permission denied: createAsset requires one of the roles") {
+		t.Fatalf("a device created an asset in a batch, result %+v", results[0])
+	}
+	if _, found := m.state["T9"]; found {
+		t.Fatal("a device created an asset")
+	}
+	m.as("alpha", CARRIERROLE).mustInvoke("updateAsset", `{"assetID":"T9","assetType":"CrudeTank"}`)
+}
+
+// every function the contract serves must be in the policy, or nobody can
+// call it
+func TestAccessPolicyCoversQueries(t *testing.T) {
+	for function := range queryFunctions {
+		if _, found := accessPolicy[function]; !found {
+			t.Errorf("query %s is missing from the access policy", function)
+		}
+	}
+}
+
+func TestCallerRoles(t *testing.T) {
+	tests := []struct {
+		roles   string
+		want    []string
+		wantErr string
+	}{
+		{"admin", []string{"admin"}, ""},
+		{"carrier,device", []string{"carrier", "device"}, ""},
+		{" bank , buyer ,", []string{"bank", "buyer"}, ""},
+		{"", nil, "the caller certificate carries no role"},
+		{" , ", nil, "the caller certificate carries no role"},
+	}
+	for _, tt := range tests {
+		m := newMockStub(t).as("caller", tt.roles)
+		roles, err := callerRoles(m)
+		wantError(t, err, tt.wantErr)
+		if len(roles) != len(tt.want) {
+			t.Fatalf("roles %q gave %v, expected %v", tt.roles, roles, tt.want)
+		}
+		for i := range roles {
+			if roles[i] != tt.want[i] {
+				t.Fatalf("roles %q gave %v, expected %v", tt.roles, roles, tt.want)
+			}
+		}
+	}
+}
+
+func TestCallerIdentity(t *testing.T) {
+	m := newMockStub(t).as("MV Nordic Star", CARRIERROLE)
+	caller, err := callerIdentity(m)
+	wantError(t, err, "")
+	if caller != "MV Nordic Star" {
+		t.Fatalf("caller %q, expected the common name of the certificate", caller)
+	}
+	_, err = callerIdentity(m.as("", CARRIERROLE))
+	wantError(t, err, "Caller certificate carries no common name")
+}
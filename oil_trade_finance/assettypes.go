@@ -0,0 +1,163 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+// Asset type registry
+//
+// Every asset is created with a type from the registry. The type declares the
+// readings its events may carry, with their units, and the alerts its rules
+// may raise, so that a tank is not judged by the humidity rules of a reefer
+// and a vessel cannot report a seal. Assets created before the registry have
+// no type; they accept every property and run every rule until they are given
+// one.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// AssetProperty is a property that the events of an asset type may carry
+type AssetProperty struct {
+	Name        string `json:"name"`
+	Unit        string `json:"unit,omitempty"`
+	Description string `json:"description"`
+}
+
+// AssetType declares the properties and rules of a kind of asset
+type AssetType struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Properties  []AssetProperty `json:"properties"` // besides the commonAssetProperties
+	Rules       AlertNameArray  `json:"rules"`      // alerts that the rules may raise for the type
+}
+
+// commonAssetProperties may be carried by the events of any asset type. The
+// properties calculated by the contract are included as they are accepted
+// and ignored.
+var commonAssetProperties = []string{
+	"assetID", "assetType", "carrier", "timestamp", "extension",
+	"txntimestamp", "txnuuid", "alerts", "compliant", "parent", "noncompliantChildren", "version",
+	"schemaVersion", "readingTimes", "expectedVersion",
+}
+
+var (
+	locationProperty       = AssetProperty{"location", "degrees", "current latitude and longitude"}
+	maxTemperatureProperty = AssetProperty{"maxTemperature", "celsius", "highest temperature measured"}
+	maxHumidityProperty    = AssetProperty{"maxHumidity", "percent", "highest relative humidity measured"}
+	sealBrokenProperty     = AssetProperty{"sealBroken", "", "true when the cargo seal was found broken"}
+)
+
+var assetTypes = []AssetType{
+	{
+		Name:        "CrudeTank",
+		Description: "A tank of crude oil, monitored for temperature and seal integrity",
+		Properties:  []AssetProperty{locationProperty, maxTemperatureProperty, sealBrokenProperty},
+		Rules:       AlertNameArray{"OVERTEMP", "TEMPRATE", "SEALTAMPER"},
+	},
+	{
+		Name:        "ReeferContainer",
+		Description: "A refrigerated container, monitored for temperature, humidity and seal integrity",
+		Properties:  []AssetProperty{locationProperty, maxTemperatureProperty, maxHumidityProperty, sealBrokenProperty},
+		Rules:       AlertNameArray{"OVERTEMP", "overhum", "TEMPRATE", "HUMRATE", "SEALTAMPER"},
+	},
+	{
+		Name:        "Vessel",
+		Description: "A ship carrying other assets, tracked for location only",
+		Properties:  []AssetProperty{locationProperty},
+		Rules:       AlertNameArray{},
+	},
+}
+
+//********************readAssetTypes********************/
+
+func (t *SimpleChaincode) readAssetTypes(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 0 {
+		return nil, errors.New("Too many arguments. Expecting none.")
+	}
+	return json.Marshal(assetTypes)
+}
+
+/*********************************  internal: asset types ****************************/
+
+// getAssetType returns the registered type of the given name
+func getAssetType(name string) (*AssetType, error) {
+	var names []string
+
+	for i := range assetTypes {
+		if assetTypes[i].Name == name {
+			return &assetTypes[i], nil
+		}
+		names = append(names, assetTypes[i].Name)
+	}
+	sort.Strings(names)
+	return nil, errors.New("Unknown asset type " + name + ", expecting one of " + strings.Join(names, ", "))
+}
+
+// checkEventProperties returns an error naming the first property of the
+// event that the asset type does not declare
+func (at *AssetType) checkEventProperties(eventJSON string) error {
+	var event map[string]interface{}
+
+	err := json.Unmarshal([]byte(eventJSON), &event)
+	if err != nil {
+		return errors.New("Unable to unmarshal input JSON data")
+	}
+	names := make([]string, 0, len(event))
+	for name := range event {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !at.hasProperty(name) {
+			return errors.New("Property " + name + " is not defined for asset type " + at.Name)
+		}
+	}
+	return nil
+}
+
+// hasProperty matches property names case insensitively, as encoding/json
+// does when the event is unmarshaled
+func (at *AssetType) hasProperty(name string) bool {
+	for _, common := range commonAssetProperties {
+		if strings.EqualFold(name, common) {
+			return true
+		}
+	}
+	for _, p := range at.Properties {
+		if strings.EqualFold(name, p.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// applicableAlerts returns the alerts that the rules may raise for an asset
+// of the type, every alert for an asset without a type
+func applicableAlerts(at *AssetType) AlertArrayInternal {
+	var applicable AlertArrayInternal
+
+	if at == nil {
+		for i := range applicable {
+			applicable[i] = true
+		}
+		return applicable
+	}
+	for _, name := range at.Rules {
+		if i, found := AlertsValue[name]; found {
+			applicable[i] = true
+		}
+	}
+	return applicable
+}
@@ -0,0 +1,107 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestReadAssetTypes(t *testing.T) {
+	var types []AssetType
+
+	m := newTestContract(t)
+	err := json.Unmarshal(m.mustQuery("readAssetTypes"), &types)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(types, assetTypes) {
+		t.Fatalf("readAssetTypes returned %+v", types)
+	}
+	_, err = m.query("readAssetTypes", "{}")
+	wantError(t, err, "Invalid arguments for readAssetTypes")
+}
+
+func TestAssetTypeProperties(t *testing.T) {
+	tests := []struct {
+		assetType string
+		event     string
+		wantErr   string
+	}{
+		{"CrudeTank", `{"assetID":"A1","maxTemperature":40,"sealBroken":false,"location":{"latitude":1,"longitude":2}}`, ""},
+		{"CrudeTank", `{"assetID":"A1","maxHumidity":40}`, "Property maxHumidity is not defined for asset type CrudeTank"},
+		{"ReeferContainer", `{"assetID":"A1","maxTemperature":4,"maxHumidity":40,"sealBroken":false}`, ""},
+		{"Vessel", `{"assetID":"A1","location":{"latitude":1,"longitude":2},"carrier":"admin","extension":{"imo":"1"}}`, ""},
+		{"Vessel", `{"assetID":"A1","maxTemperature":4}`, "Property maxTemperature is not defined for asset type Vessel"},
+		{"Vessel", `{"assetID":"A1","sealBroken":true}`, "Property sealBroken is not defined for asset type Vessel"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.assetType+" "+tt.event, func(t *testing.T) {
+			m := newTestContract(t)
+			m.mustInvoke("createAsset", `{"assetID":"A1","assetType":"`+tt.assetType+`"}`)
+			_, err := m.invoke("updateAsset", tt.event)
+			wantError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestApplicableAlerts(t *testing.T) {
+	tests := []struct {
+		assetType string
+		want      []string
+	}{
+		{"CrudeTank", []string{"OVERTEMP", "SEALTAMPER", "TEMPRATE"}},
+		{"ReeferContainer", []string{"HUMRATE", "OVERTEMP", "SEALTAMPER", "TEMPRATE", "overhum"}},
+		{"Vessel", nil},
+		{"", []string{"HUMRATE", "OVERTEMP", "SEALTAMPER", "TEMPRATE", "overhum"}},
+	}
+	for _, tt := range tests {
+		var at *AssetType
+		var err error
+
+		if tt.assetType != "" {
+			at, err = getAssetType(tt.assetType)
+			wantError(t, err, "")
+		}
+		applicable := applicableAlerts(at)
+		var names AlertNameArray
+		for i, on := range applicable {
+			if on {
+				names = append(names, AlertsName[i])
+			}
+		}
+		if got := alertNames(names); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q applies %v, expected %v", tt.assetType, got, tt.want)
+		}
+	}
+}
+
+// an asset created before the registry runs every rule until it is given a
+// type, which it can be given once
+func TestUntypedAsset(t *testing.T) {
+	m := newTestContract(t)
+	m.state["L1"] = []byte(`{"assetID":"L1","maxTemperature":20,"schemaVersion":"1.1","version":3}`)
+	m.mustInvoke("updateAsset", `{"assetID":"L1","maxHumidity":90}`)
+	if got := activeAlerts(m.asset("L1")); !reflect.DeepEqual(got, []string{"overhum"}) {
+		t.Fatalf("active alerts %v, expected overhum", got)
+	}
+	// the type judges the events from then on, not what is already stored
+	m.mustInvoke("updateAsset", `{"assetID":"L1","assetType":"CrudeTank"}`)
+	state := m.asset("L1")
+	if *state.AssetType != "CrudeTank" || activeAlerts(state) != nil || !*state.Compliance {
+		t.Fatalf("unexpected state %+v", state)
+	}
+	_, err := m.invoke("updateAsset", `{"assetID":"L1","maxHumidity":95}`)
+	wantError(t, err, "Property maxHumidity is not defined for asset type CrudeTank")
+	_, err = m.invoke("updateAsset", `{"assetID":"L1","assetType":"ReeferContainer"}`)
+	wantError(t, err, "The assetType of asset L1 cannot be changed")
+}
@@ -0,0 +1,186 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+// Batch event ingestion
+//
+// updateAssets takes an array of events, for any number of assets, and runs
+// each of them through createOrUpdateAsset in device timestamp order. A bad
+// event does not fail the batch; its error is reported in the result and none
+// of its writes reach the ledger.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// BatchResult reports the outcome of one event in a batch
+type BatchResult struct {
+	Index   int    `json:"index"`             // position of the event in the batch
+	AssetID string `json:"assetID,omitempty"` // asset the event is for, when it could be read
+	Applied int    `json:"applied"`           // order in which the event was applied, from 0
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+//******************** updateAssets ********************/
+
+func (t *SimpleChaincode) updateAssets(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	var events []json.RawMessage
+
+	if len(args) != 1 {
+		return nil, errors.New("Incorrect number of arguments. Expecting a JSON array of events")
+	}
+	err := json.Unmarshal([]byte(args[0]), &events)
+	if err != nil {
+		return nil, errors.New("Unable to unmarshal input JSON data, expecting an array of events")
+	}
+	results := make([]BatchResult, len(events))
+	order := make([]int, 0, len(events))
+	eventTimes := make([]time.Time, len(events))
+	timed := make([]bool, len(events))
+	for i := range events {
+		results[i].Index = i
+		results[i].Applied = -1
+		var key struct {
+			AssetID *string `json:"assetID"`
+		}
+		if err := json.Unmarshal(events[i], &key); err == nil && key.AssetID != nil {
+			results[i].AssetID = *key.AssetID
+		}
+		if err := validateEvent(events[i]); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		var event AssetState
+		if err := json.Unmarshal(events[i], &event); err != nil {
+			results[i].Error = "Unable to unmarshal input JSON data"
+			continue
+		}
+		if event.Timestamp != nil {
+			eventTimes[i], err = time.Parse(time.RFC3339Nano, *event.Timestamp)
+			if err != nil {
+				results[i].Error = "Device timestamp is not an RFC3339 timestamp: " + *event.Timestamp
+				continue
+			}
+			timed[i] = true
+		}
+		order = append(order, i)
+	}
+	// timed events go first in device time, then those without a device
+	// timestamp in batch order, however far the device clocks run ahead
+	sort.SliceStable(order, func(a, b int) bool {
+		if timed[order[a]] != timed[order[b]] {
+			return timed[order[a]]
+		}
+		return eventTimes[order[a]].Before(eventTimes[order[b]])
+	})
+
+	batch := newOverlayStub(stub)
+	for applied, i := range order {
+		results[i].Applied = applied
+		// each event writes to its own overlay, which is only kept if the
+		// event succeeds
+		eventStub := newOverlayStub(batch)
+		_, err := t.createOrUpdateAsset(eventStub, []string{string(events[i])})
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		err = eventStub.commit()
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		results[i].Success = true
+	}
+	err = batch.commit()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(results)
+}
+
+/*********************************  internal: overlayStub ****************************/
+
+// overlayStub buffers the writes made through it and serves them back to
+// later reads, so that events applied in one transaction see each other's
+// results. Nothing reaches the underlying stub until commit.
+type overlayStub struct {
+	shim.ChaincodeStubInterface
+	writes  map[string][]byte
+	deletes map[string]bool
+}
+
+func newOverlayStub(stub shim.ChaincodeStubInterface) *overlayStub {
+	return &overlayStub{
+		ChaincodeStubInterface: stub,
+		writes:                 make(map[string][]byte),
+		deletes:                make(map[string]bool),
+	}
+}
+
+// GetState returns the buffered value of a key if it was written
+func (o *overlayStub) GetState(key string) ([]byte, error) {
+	if o.deletes[key] {
+		return nil, nil
+	}
+	if value, found := o.writes[key]; found {
+		return value, nil
+	}
+	return o.ChaincodeStubInterface.GetState(key)
+}
+
+// PutState buffers a write
+func (o *overlayStub) PutState(key string, value []byte) error {
+	delete(o.deletes, key)
+	o.writes[key] = value
+	return nil
+}
+
+// DelState buffers a delete
+func (o *overlayStub) DelState(key string) error {
+	delete(o.writes, key)
+	o.deletes[key] = true
+	return nil
+}
+
+// commit passes the buffered writes and deletes on to the underlying stub,
+// in key order so that every peer issues them identically
+func (o *overlayStub) commit() error {
+	keys := make([]string, 0, len(o.writes)+len(o.deletes))
+	for key := range o.writes {
+		keys = append(keys, key)
+	}
+	for key := range o.deletes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if o.deletes[key] {
+			if err := o.ChaincodeStubInterface.DelState(key); err != nil {
+				return errors.New("DELSTATE failed! : " + fmt.Sprint(err))
+			}
+			continue
+		}
+		if err := o.ChaincodeStubInterface.PutState(key, o.writes[key]); err != nil {
+			return errors.New("PUT ledger state failed: " + fmt.Sprint(err))
+		}
+	}
+	o.writes = make(map[string][]byte)
+	o.deletes = make(map[string]bool)
+	return nil
+}
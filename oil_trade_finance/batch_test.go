@@ -0,0 +1,129 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUpdateAssets(t *testing.T) {
+	var results []BatchResult
+
+	m := newTestContract(t)
+	m.mustInvoke("createAsset", `{"assetID":"T1","assetType":"CrudeTank","maxTemperature":20,"timestamp":"2017-03-19T00:00:00Z"}`)
+	batch := `[
+		{"assetID":"T1","maxTemperature":22,"timestamp":"2017-03-19T03:00:00Z"},
+		{"assetID":"T2","assetType":"CrudeTank","maxTemperature":30},
+		{"assetID":"T1","maxTemperature":21,"timestamp":"2017-03-19T01:00:00Z"},
+		{"assetID":"T1","maxTemperature":"warm"},
+		{"assetID":"T3","maxTemperature":30},
+		{"assetID":"T1","timestamp":"yesterday"},
+		{"assetID":"T1","maxTemperature":70,"timestamp":"2017-03-19T02:00:00Z"}
+	]`
+	err := json.Unmarshal(m.mustInvoke("updateAssets", batch), &results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		applied int
+		success bool
+		err     string
+	}{
+		{2, true, ""},
+		{3, true, ""},
+		{0, true, ""},
+		{-1, false, "Invalid event: maxTemperature: expecting"},
+		{4, false, "An assetType is mandatory to create an asset"},
+		{-1, false, "Invalid event: timestamp"},
+		{1, true, ""},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("%d results for %d events", len(results), len(want))
+	}
+	for i, w := range want {
+		r := results[i]
+		if r.Index != i || r.Applied != w.applied || r.Success != w.success || !strings.Contains(r.Error, w.err) || (w.err == "") != (r.Error == "") {
+			t.Errorf("event %d: result %+v, expected applied %d, success %v, error %q", i, r, w.applied, w.success, w.err)
+		}
+	}
+	if results[4].AssetID != "T3" {
+		t.Errorf("the result of a failed event names asset %q, expected T3", results[4].AssetID)
+	}
+
+	// the last reading in device time is the stored one, the overtemp it
+	// passed through was cleared while the steep change still stands
+	t1 := m.asset("T1")
+	if *t1.MaxTemperature != 22 || *t1.Version != 4 || *t1.Timestamp != "2017-03-19T03:00:00Z" {
+		t.Fatalf("unexpected state of T1 %+v", t1)
+	}
+	if got := alertNames(t1.Alerts.Cleared); !reflect.DeepEqual(got, []string{"OVERTEMP"}) {
+		t.Fatalf("T1 cleared %v, expected OVERTEMP", got)
+	}
+	if got := activeAlerts(t1); !reflect.DeepEqual(got, []string{"TEMPRATE"}) {
+		t.Fatalf("T1 active alerts %v, expected TEMPRATE", got)
+	}
+	if *m.asset("T2").MaxTemperature != 30 {
+		t.Fatal("T2 was not created by the batch")
+	}
+	if _, found := m.state["T3"]; found {
+		t.Fatal("a failed event wrote to the ledger")
+	}
+}
+
+func TestUpdateAssetsUntimedLast(t *testing.T) {
+	var results []BatchResult
+
+	m := newTestContract(t)
+	m.mustInvoke("createAsset", `{"assetID":"T1","assetType":"CrudeTank","maxTemperature":20}`)
+	// a device clock running ahead of the transaction does not put its
+	// reading after the untimed ones
+	ahead := m.txTime.Add(48 * time.Hour).Format(time.RFC3339)
+	batch := `[
+		{"assetID":"T1","maxTemperature":30},
+		{"assetID":"T1","maxTemperature":25,"timestamp":"` + ahead + `"},
+		{"assetID":"T1","maxTemperature":35}
+	]`
+	err := json.Unmarshal(m.mustInvoke("updateAssets", batch), &results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []int{1, 0, 2} {
+		if results[i].Applied != want || !results[i].Success {
+			t.Errorf("event %d: result %+v, expected applied %d", i, results[i], want)
+		}
+	}
+	if t1 := m.asset("T1"); *t1.MaxTemperature != 35 {
+		t.Fatalf("stored temperature %v, expected the last untimed 35", *t1.MaxTemperature)
+	}
+}
+
+func TestUpdateAssetsArguments(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"empty batch", []string{`[]`}, ""},
+		{"not an array", []string{`{"assetID":"T1"}`}, "Invalid arguments for updateAssets"},
+		{"no arguments", nil, "Invalid arguments for updateAssets"},
+		{"two batches", []string{`[]`, `[]`}, "Invalid arguments for updateAssets"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTestContract(t).invoke("updateAssets", tt.args...)
+			wantError(t, err, tt.wantErr)
+		})
+	}
+}
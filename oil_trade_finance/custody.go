@@ -0,0 +1,276 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+// Chain of custody
+//
+// The carrier in possession of an asset proposes a handoff to the next
+// carrier, who accepts it. Each step is made under the caller's own identity,
+// which must match the carrier named for that step. Accepting a handoff
+// changes the asset's carrier and appends the transfer, with a snapshot of the
+// asset's condition at that moment, to the custody chain of the asset, so that
+// damage can be attributed to the leg during which it happened.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// CUSTODYCHAINKEYPREFIX prefixes the asset ID to store its completed custody transfers
+const CUSTODYCHAINKEYPREFIX string = "CustodyChain:"
+
+// CUSTODYHANDOFFKEYPREFIX prefixes the asset ID to store its pending handoff
+const CUSTODYHANDOFFKEYPREFIX string = "CustodyHandoff:"
+
+// CustodyTransfer is one handoff of an asset from a carrier to the next
+type CustodyTransfer struct {
+	AssetID         string         `json:"assetID"`
+	FromCarrier     string         `json:"fromCarrier"`
+	ToCarrier       string         `json:"toCarrier"`
+	ProposedTxnID   string         `json:"proposedTxnuuid"`
+	ProposedAt      string         `json:"proposedTxntimestamp"`
+	AcceptedTxnID   string         `json:"acceptedTxnuuid,omitempty"`
+	AcceptedAt      string         `json:"acceptedTxntimestamp,omitempty"`
+	Location        *Geolocation   `json:"location,omitempty"`        // where the asset was at acceptance
+	ActiveAlerts    AlertNameArray `json:"activeAlerts,omitempty"`    // alerts in force at acceptance
+	Compliant       *bool          `json:"compliant,omitempty"`       // compliance at acceptance
+	ProposalComment string         `json:"proposalComment,omitempty"` // free text from the proposing carrier
+}
+
+// CustodyChain is the custody history of an asset
+type CustodyChain struct {
+	AssetID   string            `json:"assetID"`
+	Transfers []CustodyTransfer `json:"transfers"`         // completed transfers, oldest first
+	Pending   *CustodyTransfer  `json:"pending,omitempty"` // handoff waiting for acceptance
+}
+
+//******************** proposeHandoff ********************/
+
+func (t *SimpleChaincode) proposeHandoff(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	var proposal struct {
+		ToCarrier *string `json:"toCarrier"`
+		Comment   string  `json:"comment"`
+	}
+
+	// validate input data for number of args, Unmarshaling to asset state and obtain asset id
+	stateIn, err := t.validateInput(args)
+	if err != nil {
+		return nil, err
+	}
+	assetID := *stateIn.AssetID
+	err = json.Unmarshal([]byte(args[0]), &proposal)
+	if err != nil {
+		return nil, errors.New("Unable to unmarshal handoff JSON data")
+	}
+	if proposal.ToCarrier == nil || strings.TrimSpace(*proposal.ToCarrier) == "" {
+		return nil, errors.New("The receiving carrier 'toCarrier' is mandatory")
+	}
+	toCarrier := strings.TrimSpace(*proposal.ToCarrier)
+
+	state, err := t.getLiveAsset(stub, assetID)
+	if err != nil {
+		return nil, err
+	}
+	if state.Carrier == nil || *state.Carrier == "" {
+		return nil, errors.New("Asset " + assetID + " has no carrier to hand it off")
+	}
+	caller, err := callerIdentity(stub)
+	if err != nil {
+		return nil, err
+	}
+	if caller != *state.Carrier {
+		return nil, errors.New("Permission denied: only the current carrier " + *state.Carrier + " may propose a handoff")
+	}
+	if toCarrier == caller {
+		return nil, errors.New("Asset " + assetID + " is already in the custody of " + toCarrier)
+	}
+
+	txnTime, err := txnTimestamp(stub)
+	if err != nil {
+		return nil, errors.New("Unable to get transaction timestamp: " + fmt.Sprint(err))
+	}
+	// a new proposal replaces any pending one
+	handoff := CustodyTransfer{
+		AssetID:         assetID,
+		FromCarrier:     caller,
+		ToCarrier:       toCarrier,
+		ProposedTxnID:   stub.GetTxID(),
+		ProposedAt:      txnTime,
+		ProposalComment: proposal.Comment,
+	}
+	handoffJSON, err := json.Marshal(handoff)
+	if err != nil {
+		return nil, errors.New("Marshal failed for custody handoff" + fmt.Sprint(err))
+	}
+	err = stub.PutState(CUSTODYHANDOFFKEYPREFIX+assetID, handoffJSON)
+	if err != nil {
+		return nil, errors.New("PUT ledger state failed: " + fmt.Sprint(err))
+	}
+	err = stub.SetEvent("custodyHandoffProposed", handoffJSON)
+	if err != nil {
+		return nil, errors.New("Unable to set custodyHandoffProposed event: " + fmt.Sprint(err))
+	}
+	return nil, nil
+}
+
+//******************** acceptHandoff ********************/
+
+func (t *SimpleChaincode) acceptHandoff(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	// validate input data for number of args, Unmarshaling to asset state and obtain asset id
+	stateIn, err := t.validateInput(args)
+	if err != nil {
+		return nil, err
+	}
+	assetID := *stateIn.AssetID
+
+	handoff, err := t.getPendingHandoff(stub, assetID)
+	if err != nil {
+		return nil, err
+	}
+	if handoff == nil {
+		return nil, errors.New("Asset " + assetID + " has no pending handoff")
+	}
+	state, err := t.getLiveAsset(stub, assetID)
+	if err != nil {
+		return nil, err
+	}
+	if state.Carrier == nil || *state.Carrier != handoff.FromCarrier {
+		// the carrier changed since the proposal, it no longer stands
+		return nil, errors.New("The pending handoff of asset " + assetID + " was proposed by a previous carrier")
+	}
+	caller, err := callerIdentity(stub)
+	if err != nil {
+		return nil, err
+	}
+	if caller != handoff.ToCarrier {
+		return nil, errors.New("Permission denied: only the receiving carrier " + handoff.ToCarrier + " may accept the handoff")
+	}
+
+	// the carrier changes like any other property, through the rules and
+	// the roll-up to the assets containing this one
+	eventJSON, err := json.Marshal(AssetState{AssetID: &assetID, Carrier: &handoff.ToCarrier})
+	if err != nil {
+		return nil, errors.New("Marshal failed for custody event" + fmt.Sprint(err))
+	}
+	patch, err := eventPatch(string(eventJSON))
+	if err != nil {
+		return nil, err
+	}
+	overlay := newOverlayStub(stub)
+	newState, err := t.writeAssetEvent(overlay, []string{string(eventJSON)}, patch)
+	if err != nil {
+		return nil, err
+	}
+	err = overlay.commit()
+	if err != nil {
+		return nil, err
+	}
+
+	// record the completed transfer with the condition it was handed over in
+	handoff.AcceptedTxnID = *newState.TxnID
+	handoff.AcceptedAt = *newState.TxnTimestamp
+	handoff.Location = newState.Location
+	handoff.Compliant = newState.Compliance
+	if newState.Alerts != nil {
+		handoff.ActiveAlerts = newState.Alerts.Active
+	}
+	chain, err := t.getCustodyTransfers(stub, assetID)
+	if err != nil {
+		return nil, err
+	}
+	chain = append(chain, *handoff)
+	chainJSON, err := json.Marshal(chain)
+	if err != nil {
+		return nil, errors.New("Marshal failed for custody chain" + fmt.Sprint(err))
+	}
+	err = stub.PutState(CUSTODYCHAINKEYPREFIX+assetID, chainJSON)
+	if err != nil {
+		return nil, errors.New("PUT ledger state failed: " + fmt.Sprint(err))
+	}
+	err = stub.DelState(CUSTODYHANDOFFKEYPREFIX + assetID)
+	if err != nil {
+		return nil, errors.New("DELSTATE failed! : " + fmt.Sprint(err))
+	}
+
+	handoffJSON, err := json.Marshal(handoff)
+	if err != nil {
+		return nil, errors.New("Marshal failed for custody transfer" + fmt.Sprint(err))
+	}
+	err = stub.SetEvent("custodyHandoffAccepted", handoffJSON)
+	if err != nil {
+		return nil, errors.New("Unable to set custodyHandoffAccepted event: " + fmt.Sprint(err))
+	}
+	return nil, nil
+}
+
+//********************readCustodyChain********************/
+
+func (t *SimpleChaincode) readCustodyChain(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	var chain CustodyChain
+
+	// validate input data for number of args, Unmarshaling to asset state and obtain asset id
+	stateIn, err := t.validateInput(args)
+	if err != nil {
+		return nil, err
+	}
+	chain.AssetID = *stateIn.AssetID
+	chain.Transfers, err = t.getCustodyTransfers(stub, chain.AssetID)
+	if err != nil {
+		return nil, err
+	}
+	chain.Pending, err = t.getPendingHandoff(stub, chain.AssetID)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(chain)
+}
+
+/*********************************  internal: custody ****************************/
+
+// getCustodyTransfers returns the completed transfers of an asset, oldest first
+func (t *SimpleChaincode) getCustodyTransfers(stub shim.ChaincodeStubInterface, assetID string) ([]CustodyTransfer, error) {
+	var transfers = make([]CustodyTransfer, 0)
+
+	chainBytes, err := stub.GetState(CUSTODYCHAINKEYPREFIX + assetID)
+	if err != nil {
+		return nil, errors.New("Unable to get custody chain from ledger: " + fmt.Sprint(err))
+	}
+	if len(chainBytes) == 0 {
+		return transfers, nil
+	}
+	err = json.Unmarshal(chainBytes, &transfers)
+	if err != nil {
+		return nil, errors.New("Unable to unmarshal custody chain obtained from ledger")
+	}
+	return transfers, nil
+}
+
+// getPendingHandoff returns the handoff waiting for acceptance, nil if none
+func (t *SimpleChaincode) getPendingHandoff(stub shim.ChaincodeStubInterface, assetID string) (*CustodyTransfer, error) {
+	var handoff CustodyTransfer
+
+	handoffBytes, err := stub.GetState(CUSTODYHANDOFFKEYPREFIX + assetID)
+	if err != nil {
+		return nil, errors.New("Unable to get custody handoff from ledger: " + fmt.Sprint(err))
+	}
+	if len(handoffBytes) == 0 {
+		return nil, nil
+	}
+	err = json.Unmarshal(handoffBytes, &handoff)
+	if err != nil {
+		return nil, errors.New("Unable to unmarshal custody handoff obtained from ledger")
+	}
+	return &handoff, nil
+}
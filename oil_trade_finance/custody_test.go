@@ -0,0 +1,133 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newCustodyContract returns a contract with tank T1 in the custody of
+// carrier alpha, who called last
+func newCustodyContract(t *testing.T) *mockStub {
+	m := newTestContract(t)
+	m.mustInvoke("createAsset", `{"assetID":"T1","assetType":"CrudeTank","carrier":"alpha","maxTemperature":70,"location":{"latitude":1,"longitude":2}}`)
+	return m.as("alpha", CARRIERROLE)
+}
+
+func TestCustodyHandoff(t *testing.T) {
+	var chain CustodyChain
+
+	m := newCustodyContract(t)
+	m.mustInvoke("proposeHandoff", `{"assetID":"T1","toCarrier":" beta ","comment":"at Rotterdam"}`)
+	if event := m.lastEvent(); event.Name != "custodyHandoffProposed" {
+		t.Fatalf("event %s, expected custodyHandoffProposed", event.Name)
+	}
+	json.Unmarshal(m.mustQuery("readCustodyChain", `{"assetID":"T1"}`), &chain)
+	if chain.Pending == nil || chain.Pending.ToCarrier != "beta" || chain.Pending.ProposalComment != "at Rotterdam" || len(chain.Transfers) != 0 {
+		t.Fatalf("unexpected custody chain after the proposal %+v", chain)
+	}
+
+	m.as("beta", CARRIERROLE).mustInvoke("acceptHandoff", `{"assetID":"T1"}`)
+	event := m.lastEvent()
+	if event.Name != "custodyHandoffAccepted" {
+		t.Fatalf("event %s, expected custodyHandoffAccepted", event.Name)
+	}
+	state := m.asset("T1")
+	if *state.Carrier != "beta" || *state.TxnID != event.TxID {
+		t.Fatalf("the handoff did not change the carrier: %+v", state)
+	}
+	chain = CustodyChain{}
+	json.Unmarshal(m.mustQuery("readCustodyChain", `{"assetID":"T1"}`), &chain)
+	if chain.Pending != nil || len(chain.Transfers) != 1 {
+		t.Fatalf("unexpected custody chain after the acceptance %+v", chain)
+	}
+	transfer := chain.Transfers[0]
+	if transfer.FromCarrier != "alpha" || transfer.ToCarrier != "beta" || transfer.AcceptedTxnID != event.TxID ||
+		*transfer.Compliant || !reflect.DeepEqual(alertNames(transfer.ActiveAlerts), []string{"OVERTEMP"}) ||
+		*transfer.Location.Latitude != 1 {
+		t.Fatalf("the transfer does not record the condition at handoff: %+v", transfer)
+	}
+
+	// the new carrier is in charge, the old one no longer
+	_, err := m.as("alpha", CARRIERROLE).invoke("updateAsset", `{"assetID":"T1","maxTemperature":20}`)
+	wantError(t, err, "Permission denied: only the carrier beta of asset T1")
+	m.as("beta", CARRIERROLE).mustInvoke("updateAsset", `{"assetID":"T1","maxTemperature":20}`)
+}
+
+func TestCustodyHandoffRefused(t *testing.T) {
+	tests := []struct {
+		name    string
+		caller  string
+		propose string
+		accept  string
+		wantErr string
+	}{
+		{"no receiving carrier", "alpha", `{"assetID":"T1"}`, "", "args[0].toCarrier: is required"},
+		{"blank receiving carrier", "alpha", `{"assetID":"T1","toCarrier":" "}`, "", "The receiving carrier 'toCarrier' is mandatory"},
+		{"not the carrier", "gamma", `{"assetID":"T1","toCarrier":"beta"}`, "", "Permission denied: only the current carrier alpha may propose a handoff"},
+		{"to itself", "alpha", `{"assetID":"T1","toCarrier":"alpha"}`, "", "Asset T1 is already in the custody of alpha"},
+		{"unknown asset", "alpha", `{"assetID":"T9","toCarrier":"beta"}`, "", "Asset T9 does not exist!"},
+		{"nothing pending", "beta", "", `{"assetID":"T1"}`, "Asset T1 has no pending handoff"},
+		{"not the receiver", "gamma", `{"assetID":"T1","toCarrier":"beta"}`, `{"assetID":"T1"}`, "Permission denied: only the receiving carrier beta may accept the handoff"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newCustodyContract(t)
+			if tt.accept == "" {
+				_, err := m.as(tt.caller, CARRIERROLE).invoke("proposeHandoff", tt.propose)
+				wantError(t, err, tt.wantErr)
+				return
+			}
+			if tt.propose != "" {
+				m.mustInvoke("proposeHandoff", tt.propose)
+			}
+			_, err := m.as(tt.caller, CARRIERROLE).invoke("acceptHandoff", tt.accept)
+			wantError(t, err, tt.wantErr)
+		})
+	}
+}
+
+// a proposal no longer stands once the asset changed carrier in another way
+func TestCustodyHandoffStale(t *testing.T) {
+	m := newCustodyContract(t)
+	m.mustInvoke("proposeHandoff", `{"assetID":"T1","toCarrier":"beta"}`)
+	m.as("admin", ADMINROLE).mustInvoke("updateAsset", `{"assetID":"T1","carrier":"gamma"}`)
+	_, err := m.as("beta", CARRIERROLE).invoke("acceptHandoff", `{"assetID":"T1"}`)
+	wantError(t, err, "The pending handoff of asset T1 was proposed by a previous carrier")
+}
+
+// the containing asset rolls up the compliance the handoff produces
+func TestCustodyHandoffRollsUp(t *testing.T) {
+	m := newCustodyContract(t)
+	m.as("admin", ADMINROLE).mustInvoke("createAsset", `{"assetID":"V1","assetType":"Vessel","carrier":"alpha"}`)
+	to := m.txTime.Add(3 * mockTxInterval).Format(time.RFC3339)
+	m.as("inspector", INSPECTORROLE).mustInvoke("suppressAlerts", `{"assetID":"T1","alerts":["OVERTEMP"],"to":"`+to+`","reason":"sensor swap"}`)
+	m.as("alpha", CARRIERROLE).mustInvoke("updateAsset", `{"assetID":"T1","maxTemperature":70}`)
+	m.mustInvoke("attachAsset", `{"assetID":"T1","parent":"V1"}`)
+	if vessel := m.asset("V1"); !*vessel.Compliance {
+		t.Fatalf("the vessel is not compliant within the suppression: %+v", vessel)
+	}
+	m.mustInvoke("proposeHandoff", `{"assetID":"T1","toCarrier":"beta"}`)
+
+	// the suppression is over when the handoff is accepted
+	m.txTime = m.txTime.Add(24 * time.Hour)
+	m.as("beta", CARRIERROLE).mustInvoke("acceptHandoff", `{"assetID":"T1"}`)
+	if *m.asset("T1").Compliance {
+		t.Fatal("the tank is compliant after the suppression")
+	}
+	vessel := m.asset("V1")
+	if *vessel.Compliance || !reflect.DeepEqual(vessel.NoncompliantChildren, []string{"T1"}) {
+		t.Fatalf("the vessel did not roll up the handoff: %+v", vessel)
+	}
+}
@@ -0,0 +1,273 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+// Asset ownership
+//
+// The role policy says who may update assets at all, ownership says which
+// assets. Only the current carrier of an asset, a device registered to the
+// asset or an admin may send events for it. An asset starts out in the
+// custody of the carrier that creates it, only an admin may create it for
+// another. The carrier itself changes through the custody handoff, outside
+// of which only an admin may change it.
+// The current carrier, or an admin, registers the devices of an asset by the
+// common name in their certificates; they stay registered across handoffs as
+// they travel with the asset.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// ASSETDEVICESKEYPREFIX prefixes the asset ID to store its registered devices
+const ASSETDEVICESKEYPREFIX string = "AssetDevices:"
+
+// DeviceRegistration names a device that may send the events of an asset
+type DeviceRegistration struct {
+	AssetID string `json:"assetID"`
+	Device  string `json:"device"` // common name in the certificate of the device
+}
+
+// AssetDevices lists the devices registered to an asset
+type AssetDevices struct {
+	AssetID string   `json:"assetID"`
+	Devices []string `json:"devices"` // in name order
+}
+
+//******************** registerDevice ********************/
+
+func (t *SimpleChaincode) registerDevice(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	registration, devices, err := t.deviceRegistration(stub, args)
+	if err != nil {
+		return nil, err
+	}
+	for _, device := range devices {
+		if device == registration.Device {
+			return nil, errors.New("Device " + device + " is already registered to asset " + registration.AssetID)
+		}
+	}
+	devices = append(devices, registration.Device)
+	sort.Strings(devices)
+	return nil, t.putAssetDevices(stub, registration.AssetID, devices)
+}
+
+//******************** deregisterDevice ********************/
+
+func (t *SimpleChaincode) deregisterDevice(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	registration, devices, err := t.deviceRegistration(stub, args)
+	if err != nil {
+		return nil, err
+	}
+	remaining := make([]string, 0, len(devices))
+	for _, device := range devices {
+		if device != registration.Device {
+			remaining = append(remaining, device)
+		}
+	}
+	if len(remaining) == len(devices) {
+		return nil, errors.New("Device " + registration.Device + " is not registered to asset " + registration.AssetID)
+	}
+	return nil, t.putAssetDevices(stub, registration.AssetID, remaining)
+}
+
+//********************readAssetDevices********************/
+
+func (t *SimpleChaincode) readAssetDevices(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	var devices AssetDevices
+
+	// validate input data for number of args, Unmarshaling to asset state and obtain asset id
+	stateIn, err := t.validateInput(args)
+	if err != nil {
+		return nil, err
+	}
+	devices.AssetID = *stateIn.AssetID
+	devices.Devices, err = t.getAssetDevices(stub, devices.AssetID)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(devices)
+}
+
+/*********************************  internal: ownership ****************************/
+
+// checkOwnership refuses an event or change for an existing asset unless the
+// caller is its carrier, one of its devices or an admin, and an event that
+// sets the carrier to anyone but the caller unless the caller is an admin
+func (t *SimpleChaincode) checkOwnership(stub shim.ChaincodeStubInterface, assetID string, patch map[string]interface{}) error {
+	var state AssetState
+
+	assetBytes, err := getAssetBytes(stub, assetID)
+	if err != nil || len(assetBytes) == 0 {
+		// anyone the policy allows may create an asset, in their own custody
+		carrier, found := patch["carrier"]
+		if !found || carrier == nil {
+			return nil
+		}
+		admin, err := callerHasRole(stub, ADMINROLE)
+		if err != nil || admin {
+			return err
+		}
+		caller, err := callerIdentity(stub)
+		if err != nil {
+			return err
+		}
+		if carrier != caller {
+			return errors.New("Permission denied: only an admin may create asset " + assetID + " in the custody of another carrier")
+		}
+		return nil
+	}
+	err = json.Unmarshal(assetBytes, &state)
+	if err != nil {
+		return errors.New("Unable to unmarshal state data obtained from ledger")
+	}
+	admin, err := callerHasRole(stub, ADMINROLE)
+	if err != nil || admin {
+		return err
+	}
+	if carrier, found := patch["carrier"]; found && (state.Carrier == nil || carrier != *state.Carrier) {
+		return errors.New("Permission denied: only an admin may change the carrier of asset " + assetID + " outside a custody handoff")
+	}
+	caller, err := callerIdentity(stub)
+	if err != nil {
+		return err
+	}
+	if state.Carrier != nil && caller == *state.Carrier {
+		return nil
+	}
+	devices, err := t.getAssetDevices(stub, assetID)
+	if err != nil {
+		return err
+	}
+	for _, device := range devices {
+		if caller == device {
+			return nil
+		}
+	}
+	if state.Carrier == nil {
+		return errors.New("Permission denied: asset " + assetID + " has no carrier, only a device registered to it may change it")
+	}
+	return errors.New("Permission denied: only the carrier " + *state.Carrier + " of asset " + assetID + " or a device registered to it may change it")
+}
+
+// creatorCustody returns the event that creates an asset with the carrier
+// filled in, an asset created without one is in the custody of its creator
+// unless an admin creates it
+func creatorCustody(stub shim.ChaincodeStubInterface, args []string, patch map[string]interface{}) ([]string, error) {
+	var event map[string]json.RawMessage
+
+	if patch["carrier"] != nil {
+		return args, nil
+	}
+	admin, err := callerHasRole(stub, ADMINROLE)
+	if err != nil || admin {
+		return args, err
+	}
+	caller, err := callerIdentity(stub)
+	if err != nil {
+		return args, err
+	}
+	err = json.Unmarshal([]byte(args[0]), &event)
+	if err != nil {
+		return args, errors.New("Unable to unmarshal input JSON data")
+	}
+	event["carrier"], _ = json.Marshal(caller)
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return args, errors.New("Marshal failed for event" + fmt.Sprint(err))
+	}
+	patch["carrier"] = caller
+	return []string{string(eventJSON)}, nil
+}
+
+// deviceRegistration reads a registration and returns it with the devices
+// already registered, once the caller is known to manage them
+func (t *SimpleChaincode) deviceRegistration(stub shim.ChaincodeStubInterface, args []string) (DeviceRegistration, []string, error) {
+	var registration DeviceRegistration
+
+	if len(args) != 1 {
+		return registration, nil, errors.New("Incorrect number of arguments. Expecting a JSON strings with mandatory assetID")
+	}
+	err := json.Unmarshal([]byte(args[0]), &registration)
+	if err != nil {
+		return registration, nil, errors.New("Unable to unmarshal device registration JSON data")
+	}
+	registration.AssetID = strings.TrimSpace(registration.AssetID)
+	if registration.AssetID == "" {
+		return registration, nil, errors.New("Asset id is mandatory in the input JSON data")
+	}
+	registration.Device = strings.TrimSpace(registration.Device)
+	if registration.Device == "" {
+		return registration, nil, errors.New("The device name is mandatory")
+	}
+	state, err := t.getLiveAsset(stub, registration.AssetID)
+	if err != nil {
+		return registration, nil, err
+	}
+	admin, err := callerHasRole(stub, ADMINROLE)
+	if err != nil {
+		return registration, nil, err
+	}
+	if !admin {
+		caller, err := callerIdentity(stub)
+		if err != nil {
+			return registration, nil, err
+		}
+		if state.Carrier == nil || caller != *state.Carrier {
+			return registration, nil, errors.New("Permission denied: only the carrier of asset " + registration.AssetID + " or an admin may manage its devices")
+		}
+	}
+	devices, err := t.getAssetDevices(stub, registration.AssetID)
+	return registration, devices, err
+}
+
+// getAssetDevices returns the devices registered to an asset
+func (t *SimpleChaincode) getAssetDevices(stub shim.ChaincodeStubInterface, assetID string) ([]string, error) {
+	var devices = make([]string, 0)
+
+	devicesBytes, err := stub.GetState(ASSETDEVICESKEYPREFIX + assetID)
+	if err != nil {
+		return nil, errors.New("Unable to get asset devices from ledger: " + fmt.Sprint(err))
+	}
+	if len(devicesBytes) == 0 {
+		return devices, nil
+	}
+	err = json.Unmarshal(devicesBytes, &devices)
+	if err != nil {
+		return nil, errors.New("Unable to unmarshal asset devices obtained from ledger")
+	}
+	return devices, nil
+}
+
+// putAssetDevices writes the devices registered to an asset, removing the
+// record when there are none
+func (t *SimpleChaincode) putAssetDevices(stub shim.ChaincodeStubInterface, assetID string, devices []string) error {
+	if len(devices) == 0 {
+		err := stub.DelState(ASSETDEVICESKEYPREFIX + assetID)
+		if err != nil {
+			return errors.New("DELSTATE failed! : " + fmt.Sprint(err))
+		}
+		return nil
+	}
+	devicesJSON, err := json.Marshal(devices)
+	if err != nil {
+		return errors.New("Marshal failed for asset devices" + fmt.Sprint(err))
+	}
+	err = stub.PutState(ASSETDEVICESKEYPREFIX+assetID, devicesJSON)
+	if err != nil {
+		return errors.New("PUT ledger state failed: " + fmt.Sprint(err))
+	}
+	return nil
+}
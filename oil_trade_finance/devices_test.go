@@ -0,0 +1,119 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDeviceRegistration(t *testing.T) {
+	m := newCustodyContract(t)
+	steps := []struct {
+		caller   string
+		roles    string
+		function string
+		args     string
+		wantErr  string
+	}{
+		{"alpha", CARRIERROLE, "registerDevice", `{"assetID":"T1","device":" sensor-2 "}`, ""},
+		{"alpha", CARRIERROLE, "registerDevice", `{"assetID":"T1","device":"sensor-1"}`, ""},
+		{"admin", ADMINROLE, "registerDevice", `{"assetID":"T1","device":"gateway"}`, ""},
+		{"alpha", CARRIERROLE, "registerDevice", `{"assetID":"T1","device":"sensor-1"}`, "Device sensor-1 is already registered to asset T1"},
+		{"beta", CARRIERROLE, "registerDevice", `{"assetID":"T1","device":"sensor-3"}`, "Permission denied: only the carrier of asset T1 or an admin may manage its devices"},
+		{"alpha", CARRIERROLE, "registerDevice", `{"assetID":"T1","device":" "}`, "The device name is mandatory"},
+		{"alpha", CARRIERROLE, "registerDevice", `{"assetID":"T9","device":"sensor-3"}`, "Asset T9 does not exist!"},
+		{"alpha", CARRIERROLE, "deregisterDevice", `{"assetID":"T1","device":"gateway"}`, ""},
+		{"alpha", CARRIERROLE, "deregisterDevice", `{"assetID":"T1","device":"gateway"}`, "Device gateway is not registered to asset T1"},
+	}
+	for i, step := range steps {
+		_, err := m.as(step.caller, step.roles).invoke(step.function, step.args)
+		if (err == nil) != (step.wantErr == "") || err != nil && !strings.Contains(err.Error(), step.wantErr) {
+			t.Fatalf("step %d, %s %s: error %v, expected %q", i, step.function, step.args, err, step.wantErr)
+		}
+	}
+	var devices AssetDevices
+	json.Unmarshal(m.mustQuery("readAssetDevices", `{"assetID":"T1"}`), &devices)
+	if devices.AssetID != "T1" || !reflect.DeepEqual(devices.Devices, []string{"sensor-1", "sensor-2"}) {
+		t.Fatalf("readAssetDevices returned %+v", devices)
+	}
+}
+
+func TestOwnership(t *testing.T) {
+	tests := []struct {
+		name    string
+		caller  string
+		roles   string
+		event   string
+		wantErr string
+	}{
+		{"carrier", "alpha", CARRIERROLE, `{"assetID":"T1","maxTemperature":20}`, ""},
+		{"registered device", "sensor-1", DEVICEROLE, `{"assetID":"T1","maxTemperature":20}`, ""},
+		{"admin", "admin", ADMINROLE, `{"assetID":"T1","maxTemperature":20}`, ""},
+		{"admin changes the carrier", "admin", ADMINROLE, `{"assetID":"T1","carrier":"beta"}`, ""},
+		{"carrier keeps itself", "alpha", CARRIERROLE, `{"assetID":"T1","carrier":"alpha"}`, ""},
+		{"other carrier", "beta", CARRIERROLE, `{"assetID":"T1","maxTemperature":20}`, "Permission denied: only the carrier alpha of asset T1 or a device registered to it may change it"},
+		{"unregistered device", "sensor-9", DEVICEROLE, `{"assetID":"T1","maxTemperature":20}`, "Permission denied"},
+		{"carrier hands off alone", "alpha", CARRIERROLE, `{"assetID":"T1","carrier":"beta"}`, "Permission denied: only an admin may change the carrier of asset T1 outside a custody handoff"},
+		{"device changes the carrier", "sensor-1", DEVICEROLE, `{"assetID":"T1","carrier":"beta"}`, "Permission denied: only an admin may change the carrier"},
+		{"anyone allowed creates", "beta", CARRIERROLE, `{"assetID":"T2","assetType":"CrudeTank","carrier":"beta"}`, ""},
+		{"carrier creates for another", "beta", CARRIERROLE, `{"assetID":"T2","assetType":"CrudeTank","carrier":"alpha"}`, "Permission denied: only an admin may create asset T2 in the custody of another carrier"},
+		{"admin creates for a carrier", "admin", ADMINROLE, `{"assetID":"T2","assetType":"CrudeTank","carrier":"alpha"}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newCustodyContract(t)
+			m.mustInvoke("registerDevice", `{"assetID":"T1","device":"sensor-1"}`)
+			_, err := m.as(tt.caller, tt.roles).invoke("updateAsset", tt.event)
+			wantError(t, err, tt.wantErr)
+		})
+	}
+}
+
+// an asset created without a carrier is in the custody of its creator,
+// unless an admin created it
+func TestCreatorCustody(t *testing.T) {
+	tests := []struct {
+		caller      string
+		roles       string
+		event       string
+		wantCarrier string
+	}{
+		{"beta", CARRIERROLE, `{"assetID":"T2","assetType":"CrudeTank"}`, "beta"},
+		{"beta", CARRIERROLE, `{"assetID":"T2","assetType":"CrudeTank","carrier":null,"maxTemperature":20}`, "beta"},
+		{"acme", SELLERROLE, `{"assetID":"T2","assetType":"CrudeTank"}`, "acme"},
+		{"admin", ADMINROLE, `{"assetID":"T2","assetType":"CrudeTank"}`, ""},
+	}
+	for _, tt := range tests {
+		m := newTestContract(t)
+		m.as(tt.caller, tt.roles).mustInvoke("createAsset", tt.event)
+		state := m.asset("T2")
+		if carrier := state.Carrier; (carrier == nil) != (tt.wantCarrier == "") || carrier != nil && *carrier != tt.wantCarrier {
+			t.Fatalf("%s created %s with carrier %v, expected %q", tt.caller, tt.event, carrier, tt.wantCarrier)
+		}
+	}
+	// the creator then owns the asset
+	m := newTestContract(t)
+	m.as("beta", CARRIERROLE).mustInvoke("updateAsset", `{"assetID":"T2","assetType":"CrudeTank"}`)
+	m.mustInvoke("updateAsset", `{"assetID":"T2","maxTemperature":20}`)
+}
+
+// a carrierless asset, from before ownership, is updated by its devices
+func TestOwnershipWithoutCarrier(t *testing.T) {
+	m := newTestContract(t)
+	m.mustInvoke("createAsset", `{"assetID":"T1","assetType":"CrudeTank"}`)
+	_, err := m.as("alpha", CARRIERROLE).invoke("updateAsset", `{"assetID":"T1","maxTemperature":20}`)
+	wantError(t, err, "Permission denied: asset T1 has no carrier, only a device registered to it may change it")
+	m.as("admin", ADMINROLE).mustInvoke("registerDevice", `{"assetID":"T1","device":"sensor-1"}`)
+	m.as("sensor-1", DEVICEROLE).mustInvoke("updateAsset", `{"assetID":"T1","maxTemperature":20}`)
+}
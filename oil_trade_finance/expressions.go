@@ -0,0 +1,603 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+// Rule condition expressions
+//
+// A condition is a boolean expression over the properties of an asset state,
+// for example:
+//
+//    maxTemperature > 45 AND inside(location, "tropical")
+//    maxHumidity > 80 OR sealBroken
+//
+// Grammar:
+//
+//    or         := and { ("OR" | "||") and }
+//    and        := not { ("AND" | "&&") not }
+//    not        := ("NOT" | "!") not | comparison
+//    comparison := operand [ ("==" | "!=" | ">" | ">=" | "<" | "<=") operand ]
+//    operand    := number | string | "true" | "false" | "(" or ")"
+//                | name [ "(" [ or { "," or } ] ")" ]
+//
+// Names are qualified property names such as location.latitude and are
+// matched without regard to case, like getObject. A property that is missing
+// from the state makes every comparison it takes part in false, and is false
+// when used on its own as a condition. Comparing values of different types is
+// an error, which the rules treat as "alert status not changed".
+//
+// Evaluation never depends on map iteration order, clocks or randomness, so
+// every peer reaches the same result for the same state.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ************************************
+// tokens
+// ************************************
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenName
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// tokenize splits an expression into tokens, decoding it as UTF-8 so that
+// names and strings may hold any letters. Positions are byte offsets.
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(expr) {
+		c, size := utf8.DecodeRuneInString(expr[i:])
+		switch {
+		case c == utf8.RuneError && size == 1:
+			return nil, fmt.Errorf("invalid UTF-8 at position %d", i)
+		case unicode.IsSpace(c):
+			i += size
+		case c == '(':
+			tokens = append(tokens, token{tokenLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokenRParen, ")", i})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{tokenComma, ",", i})
+			i++
+		case c == '"' || c == '\'':
+			end := strings.IndexRune(expr[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			tokens = append(tokens, token{tokenString, expr[i+1 : i+1+end], i})
+			i += end + 2
+		case isDigit(c) || (c == '-' && i+1 < len(expr) && isDigit(rune(expr[i+1]))) || c == '.':
+			start := i
+			i++
+			for i < len(expr) && (isDigit(rune(expr[i])) || strings.ContainsRune(".eE", rune(expr[i])) ||
+				((expr[i] == '-' || expr[i] == '+') && (expr[i-1] == 'e' || expr[i-1] == 'E'))) {
+				i++
+			}
+			tokens = append(tokens, token{tokenNumber, expr[start:i], start})
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(expr) {
+				c, size = utf8.DecodeRuneInString(expr[i:])
+				if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' && c != '.' {
+					break
+				}
+				i += size
+			}
+			tokens = append(tokens, token{tokenName, expr[start:i], start})
+		default:
+			start := i
+			for _, op := range []string{"==", "!=", ">=", "<=", "&&", "||", ">", "<", "!"} {
+				if strings.HasPrefix(expr[i:], op) {
+					tokens = append(tokens, token{tokenOperator, op, start})
+					i += len(op)
+					break
+				}
+			}
+			if i == start {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+			}
+		}
+	}
+	tokens = append(tokens, token{tokenEOF, "", len(expr)})
+	return tokens, nil
+}
+
+// isDigit is true for the ASCII digits, the only ones numbers are written with
+func isDigit(c rune) bool {
+	return c >= '0' && c <= '9'
+}
+
+// ************************************
+// syntax tree
+// ************************************
+
+// Condition is a parsed rule condition, ready to be evaluated against states
+type Condition struct {
+	source string
+	root   exprNode
+}
+
+type exprNode interface {
+	eval(a *ArgsMap) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+type nameNode struct {
+	qname string
+}
+
+type notNode struct {
+	operand exprNode
+}
+
+type logicalNode struct {
+	op          string // AND or OR
+	left, right exprNode
+}
+
+type comparisonNode struct {
+	op          string
+	left, right exprNode
+}
+
+type callNode struct {
+	name string
+	args []exprNode
+}
+
+// missing is the value of a property that is not present in the state
+type missingValue struct{}
+
+var missing = missingValue{}
+
+// ************************************
+// parser
+// ************************************
+
+type exprParser struct {
+	tokens []token
+	pos    int
+}
+
+// ParseCondition parses a rule condition expression
+func ParseCondition(expr string) (*Condition, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, errors.New("Condition " + strconv.Quote(expr) + ": " + err.Error())
+	}
+	p := &exprParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && p.peek().kind != tokenEOF {
+		err = fmt.Errorf("unexpected %q at position %d", p.peek().text, p.peek().pos)
+	}
+	if err != nil {
+		return nil, errors.New("Condition " + strconv.Quote(expr) + ": " + err.Error())
+	}
+	return &Condition{expr, root}, nil
+}
+
+func (p *exprParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// isKeyword matches an operator token or a case insensitive keyword
+func (p *exprParser) isKeyword(symbol string, keyword string) bool {
+	t := p.peek()
+	return (t.kind == tokenOperator && t.text == symbol) ||
+		(t.kind == tokenName && strings.EqualFold(t.text, keyword))
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("||", "OR") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{"OR", left, right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("&&", "AND") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{"AND", left, right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseNot() (exprNode, error) {
+	if p.isKeyword("!", "NOT") {
+		p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	if t.kind == tokenOperator {
+		switch t.text {
+		case "==", "!=", ">", ">=", "<", "<=":
+			p.next()
+			right, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			return &comparisonNode{t.text, left, right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseOperand() (exprNode, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", t.text, t.pos)
+		}
+		return &literalNode{f}, nil
+	case tokenString:
+		return &literalNode{t.text}, nil
+	case tokenLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next().kind != tokenRParen {
+			return nil, fmt.Errorf("missing ) for ( at position %d", t.pos)
+		}
+		return inner, nil
+	case tokenName:
+		switch strings.ToLower(t.text) {
+		case "true":
+			return &literalNode{true}, nil
+		case "false":
+			return &literalNode{false}, nil
+		case "and", "or", "not":
+			return nil, fmt.Errorf("unexpected %s at position %d", t.text, t.pos)
+		}
+		if p.peek().kind != tokenLParen {
+			return &nameNode{t.text}, nil
+		}
+		p.next()
+		call := &callNode{name: strings.ToLower(t.text)}
+		if _, found := conditionFunctions[call.name]; !found {
+			return nil, fmt.Errorf("unknown function %s at position %d", t.text, t.pos)
+		}
+		if p.peek().kind == tokenRParen {
+			p.next()
+			return call, nil
+		}
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			sep := p.next()
+			if sep.kind == tokenRParen {
+				return call, nil
+			}
+			if sep.kind != tokenComma {
+				return nil, fmt.Errorf("expected , or ) at position %d", sep.pos)
+			}
+		}
+	case tokenEOF:
+		return nil, errors.New("unexpected end of condition")
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos)
+}
+
+// ************************************
+// evaluation
+// ************************************
+
+// Evaluate returns whether the condition holds for the state
+func (c *Condition) Evaluate(a *ArgsMap) (bool, error) {
+	v, err := c.root.eval(a)
+	if err != nil {
+		return false, errors.New("Condition " + strconv.Quote(c.source) + ": " + err.Error())
+	}
+	b, err := asCondition(v)
+	if err != nil {
+		return false, errors.New("Condition " + strconv.Quote(c.source) + ": " + err.Error())
+	}
+	return b, nil
+}
+
+// String returns the source text of the condition
+func (c *Condition) String() string {
+	return c.source
+}
+
+// asCondition interprets a value in a boolean position
+func asCondition(v interface{}) (bool, error) {
+	switch b := v.(type) {
+	case bool:
+		return b, nil
+	case missingValue:
+		return false, nil
+	}
+	return false, fmt.Errorf("%v is not a boolean", v)
+}
+
+func (n *literalNode) eval(a *ArgsMap) (interface{}, error) {
+	return n.value, nil
+}
+
+func (n *nameNode) eval(a *ArgsMap) (interface{}, error) {
+	v, found := getObject(*a, n.qname)
+	if !found || v == nil {
+		return missing, nil
+	}
+	return v, nil
+}
+
+func (n *notNode) eval(a *ArgsMap) (interface{}, error) {
+	v, err := n.operand.eval(a)
+	if err != nil {
+		return nil, err
+	}
+	b, err := asCondition(v)
+	if err != nil {
+		return nil, err
+	}
+	return !b, nil
+}
+
+func (n *logicalNode) eval(a *ArgsMap) (interface{}, error) {
+	lv, err := n.left.eval(a)
+	if err != nil {
+		return nil, err
+	}
+	left, err := asCondition(lv)
+	if err != nil {
+		return nil, err
+	}
+	// short circuit, so that the right side may rely on the left one
+	if n.op == "AND" && !left {
+		return false, nil
+	}
+	if n.op == "OR" && left {
+		return true, nil
+	}
+	rv, err := n.right.eval(a)
+	if err != nil {
+		return nil, err
+	}
+	return asCondition(rv)
+}
+
+func (n *comparisonNode) eval(a *ArgsMap) (interface{}, error) {
+	lv, err := n.left.eval(a)
+	if err != nil {
+		return nil, err
+	}
+	rv, err := n.right.eval(a)
+	if err != nil {
+		return nil, err
+	}
+	if lv == missing || rv == missing {
+		return false, nil
+	}
+	switch l := lv.(type) {
+	case float64:
+		r, ok := rv.(float64)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare number %v with %v", l, rv)
+		}
+		switch n.op {
+		case "==":
+			return l == r, nil
+		case "!=":
+			return l != r, nil
+		case ">":
+			return l > r, nil
+		case ">=":
+			return l >= r, nil
+		case "<":
+			return l < r, nil
+		case "<=":
+			return l <= r, nil
+		}
+	case string:
+		r, ok := rv.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare string %q with %v", l, rv)
+		}
+		switch n.op {
+		case "==":
+			return l == r, nil
+		case "!=":
+			return l != r, nil
+		case ">":
+			return l > r, nil
+		case ">=":
+			return l >= r, nil
+		case "<":
+			return l < r, nil
+		case "<=":
+			return l <= r, nil
+		}
+	case bool:
+		r, ok := rv.(bool)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare boolean %v with %v", l, rv)
+		}
+		switch n.op {
+		case "==":
+			return l == r, nil
+		case "!=":
+			return l != r, nil
+		}
+		return nil, fmt.Errorf("operator %s is not defined for booleans", n.op)
+	}
+	return nil, fmt.Errorf("cannot compare %v with %v", lv, rv)
+}
+
+func (n *callNode) eval(a *ArgsMap) (interface{}, error) {
+	args := make([]interface{}, len(n.args))
+	for i := range n.args {
+		v, err := n.args[i].eval(a)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	return conditionFunctions[n.name](args)
+}
+
+// ************************************
+// functions
+// ************************************
+
+// geoZone is a latitude / longitude bounding box
+type geoZone struct {
+	minLatitude, minLongitude float64
+	maxLatitude, maxLongitude float64
+}
+
+// geoZones are the named zones known to inside(), a zone that spans both
+// hemispheres is made of one box in each
+var geoZones = map[string][]geoZone{
+	"tropical":  {{-23.44, -180, 23.44, 180}},
+	"temperate": {{23.44, -180, 66.56, 180}, {-66.56, -180, -23.44, 180}},
+	"arctic":    {{66.56, -180, 90, 180}},
+	"antarctic": {{-90, -180, -66.56, 180}},
+}
+
+var conditionFunctions = map[string]func(args []interface{}) (interface{}, error){
+	// exists(name) is true when the property is present in the state
+	"exists": func(args []interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, errors.New("exists expects 1 argument")
+		}
+		return args[0] != missing, nil
+	},
+	// inside(location, "zone") is true when the location lies in a named zone
+	"inside": func(args []interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return nil, errors.New("inside expects 2 arguments, a location and a zone name")
+		}
+		name, ok := args[1].(string)
+		if !ok {
+			return nil, errors.New("inside expects a zone name as its second argument")
+		}
+		zone, found := geoZones[strings.ToLower(name)]
+		if !found {
+			return nil, errors.New("inside: unknown zone " + name)
+		}
+		for _, box := range zone {
+			in, err := inZone(args[0], box)
+			if err != nil || in == true {
+				return in, err
+			}
+		}
+		return false, nil
+	},
+	// within(location, minLat, minLon, maxLat, maxLon) is true when the
+	// location lies in the bounding box
+	"within": func(args []interface{}) (interface{}, error) {
+		if len(args) != 5 {
+			return nil, errors.New("within expects 5 arguments, a location and a bounding box")
+		}
+		var box [4]float64
+		for i := range box {
+			f, ok := args[i+1].(float64)
+			if !ok {
+				return nil, errors.New("within expects numbers for the bounding box")
+			}
+			box[i] = f
+		}
+		return inZone(args[0], geoZone{box[0], box[1], box[2], box[3]})
+	},
+}
+
+func inZone(location interface{}, zone geoZone) (interface{}, error) {
+	if location == missing {
+		return false, nil
+	}
+	if _, ok := location.(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("%v is not a location", location)
+	}
+	lat, latFound := getObject(location, "latitude")
+	lon, lonFound := getObject(location, "longitude")
+	if !latFound || !lonFound {
+		return false, nil
+	}
+	latitude, ok := lat.(float64)
+	if !ok {
+		return nil, errors.New("latitude is not a number")
+	}
+	longitude, ok := lon.(float64)
+	if !ok {
+		return nil, errors.New("longitude is not a number")
+	}
+	return latitude >= zone.minLatitude && latitude <= zone.maxLatitude &&
+		longitude >= zone.minLongitude && longitude <= zone.maxLongitude, nil
+}
@@ -0,0 +1,149 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConditionEvaluate(t *testing.T) {
+	state := ArgsMap{
+		"assetID":        "R1",
+		"maxTemperature": 61.0,
+		"maxHumidity":    80.0,
+		"sealBroken":     false,
+		"carrier":        "alpha",
+		"extension":      nil,
+		"location":       map[string]interface{}{"latitude": 1.3, "longitude": 103.8},
+		"température":    61.0,
+		"port":           "São Tomé",
+	}
+	tests := []struct {
+		expr    string
+		want    bool
+		wantErr string
+	}{
+		{"maxTemperature > 60", true, ""},
+		{"maxTemperature >= 61 AND maxHumidity <= 80", true, ""},
+		{"maxTemperature < 60 OR maxHumidity == 80", true, ""},
+		{"maxTemperature > 60 && maxHumidity > 80", false, ""},
+		{"maxTemperature < 60 || maxHumidity != 80", false, ""},
+		{"NOT sealBroken", true, ""},
+		{"!sealBroken && !(maxTemperature < 0)", true, ""},
+		{"sealBroken == false", true, ""},
+		{"not sealBroken and carrier == \"alpha\"", true, ""},
+		{"carrier > \"aardvark\"", true, ""},
+		{"MaxTemperature > 60", true, ""},
+		{"location.latitude < 23.44", true, ""},
+		{"inside(location, \"tropical\")", true, ""},
+		{"inside(location, \"Arctic\")", false, ""},
+		{"inside(location, \"temperate\")", false, ""},
+		{"within(location, 0, 100, 2, 104)", true, ""},
+		{"within(location, -1, -1, 1, 1)", false, ""},
+		{"exists(carrier)", true, ""},
+		{"exists(parent)", false, ""},
+		{"exists(extension)", false, ""},
+		{"parent", false, ""},
+		{"parent == \"V1\"", false, ""},
+		{"NOT parent", true, ""},
+		{"inside(destination, \"tropical\")", false, ""},
+		{"maxHumidity > 50 OR maxTemperature > \"hot\"", true, ""},
+		{"maxTemperature > \"hot\"", false, "cannot compare number 61 with hot"},
+		{"carrier == 1", false, "cannot compare string"},
+		{"sealBroken > true", false, "operator > is not defined for booleans"},
+		{"carrier", false, "alpha is not a boolean"},
+		{"inside(location, \"moon\")", false, "inside: unknown zone moon"},
+		{"inside(location)", false, "inside expects 2 arguments"},
+		{"inside(carrier, \"tropical\")", false, "alpha is not a location"},
+		{"within(location, 0, 0, \"north\", 1)", false, "within expects numbers for the bounding box"},
+		{"température > 60", true, ""},
+		{"port == \"São Tomé\" AND inside(location, \"tropical\")", true, ""},
+		{"port != 'São Tomé'", false, ""},
+	}
+	for _, tt := range tests {
+		c, err := ParseCondition(tt.expr)
+		if err != nil {
+			t.Errorf("%s: %v", tt.expr, err)
+			continue
+		}
+		got, err := c.Evaluate(&state)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: error %v, expected one containing %q", tt.expr, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: %v, %v, expected %v", tt.expr, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseConditionErrors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{"", "unexpected end of condition"},
+		{"maxTemperature >", "unexpected end of condition"},
+		{"maxTemperature > 60 60", "unexpected \"60\" at position 20"},
+		{"(maxTemperature > 60", "missing ) for ( at position 0"},
+		{"maxTemperature # 60", "unexpected character '#' at position 15"},
+		{"carrier == \"alpha", "unterminated string"},
+		{"AND maxTemperature", "unexpected AND at position 0"},
+		{"outside(location)", "unknown function outside at position 0"},
+		{"inside(location \"tropical\")", "expected , or ) at position 16"},
+		{"1.2.3 > 0", "invalid number \"1.2.3\" at position 0"},
+		{"maxTemperature ≥ 60", "unexpected character '≥' at position 15"},
+		{"carrier == \xff", "invalid UTF-8 at position 11"},
+	}
+	for _, tt := range tests {
+		_, err := ParseCondition(tt.expr)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%q: error %v, expected one containing %q", tt.expr, err, tt.wantErr)
+		}
+	}
+}
+
+// every condition the rules use is parsed as written
+func TestAlertConditionsParse(t *testing.T) {
+	for _, r := range alertConditions {
+		if r.parsed.String() != r.condition {
+			t.Errorf("rule %s parsed as %q", r.alert, r.parsed.String())
+		}
+	}
+}
+
+func TestGeoZones(t *testing.T) {
+	tests := []struct {
+		latitude float64
+		want     string
+	}{
+		{89, "arctic"},
+		{51.9, "temperate"},
+		{1.3, "tropical"},
+		{-23.9, "temperate"},
+		{-70, "antarctic"},
+	}
+	for _, tt := range tests {
+		location := map[string]interface{}{"latitude": tt.latitude, "longitude": 4.1}
+		for name := range geoZones {
+			got, err := conditionFunctions["inside"]([]interface{}{location, name})
+			if err != nil {
+				t.Fatalf("latitude %v: %v", tt.latitude, err)
+			}
+			if got != (name == tt.want) {
+				t.Errorf("latitude %v: inside %s is %v", tt.latitude, name, got)
+			}
+		}
+	}
+}
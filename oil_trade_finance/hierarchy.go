@@ -0,0 +1,441 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+// Hierarchical assets
+//
+// An asset can be attached to a parent asset that contains it, e.g. a cargo
+// tank to the vessel carrying it. A location reported for a parent is passed
+// down to everything it contains, and a parent is only compliant when all of
+// the assets it contains are; the ones that are not are listed in its
+// noncompliantChildren property.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// ASSETCHILDRENKEYPREFIX prefixes the asset ID to store the IDs of the assets it contains
+const ASSETCHILDRENKEYPREFIX string = "AssetChildren:"
+
+// AssetTree is an asset with the assets it contains
+type AssetTree struct {
+	State    AssetState  `json:"state"`
+	Children []AssetTree `json:"children"`
+}
+
+//******************** attachAsset ********************/
+
+func (t *SimpleChaincode) attachAsset(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	var attach struct {
+		Parent *string `json:"parent"`
+	}
+
+	// validate input data for number of args, Unmarshaling to asset state and obtain asset id
+	stateIn, err := t.validateInput(args)
+	if err != nil {
+		return nil, err
+	}
+	assetID := *stateIn.AssetID
+	err = json.Unmarshal([]byte(args[0]), &attach)
+	if err != nil {
+		return nil, errors.New("Unable to unmarshal input JSON data")
+	}
+	if attach.Parent == nil || *attach.Parent == "" {
+		return nil, errors.New("The containing asset 'parent' is mandatory")
+	}
+	parentID := *attach.Parent
+
+	overlay := newOverlayStub(stub)
+	// the caller must be in charge of both, or it could take in the asset of
+	// another carrier and move it with its own
+	for _, id := range []string{assetID, parentID} {
+		err = t.checkOwnership(overlay, id, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	state, err := t.getLiveAsset(overlay, assetID)
+	if err != nil {
+		return nil, err
+	}
+	// the parent may not be the asset itself or one of its descendants
+	for ancestorID := &parentID; ancestorID != nil; {
+		if *ancestorID == assetID {
+			return nil, errors.New("Asset " + assetID + " cannot be contained in itself")
+		}
+		ancestor, err := t.getLiveAsset(overlay, *ancestorID)
+		if err != nil {
+			return nil, err
+		}
+		ancestorID = ancestor.Parent
+	}
+
+	if state.Parent != nil {
+		if *state.Parent == parentID {
+			return nil, nil
+		}
+		// moving between containers
+		err = t.detachFromParent(overlay, &state)
+		if err != nil {
+			return nil, err
+		}
+	}
+	children, err := t.getChildren(overlay, parentID)
+	if err != nil {
+		return nil, err
+	}
+	children = append(children, assetID)
+	err = t.putChildren(overlay, parentID, children)
+	if err != nil {
+		return nil, err
+	}
+	state.Parent = &parentID
+	err = t.putAssetState(overlay, state)
+	if err != nil {
+		return nil, err
+	}
+	err = t.rollUpAncestors(overlay, state.Parent)
+	if err != nil {
+		return nil, err
+	}
+	return nil, overlay.commit()
+}
+
+//******************** detachAsset ********************/
+
+func (t *SimpleChaincode) detachAsset(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	// validate input data for number of args, Unmarshaling to asset state and obtain asset id
+	stateIn, err := t.validateInput(args)
+	if err != nil {
+		return nil, err
+	}
+	overlay := newOverlayStub(stub)
+	state, err := t.getLiveAsset(overlay, *stateIn.AssetID)
+	if err != nil {
+		return nil, err
+	}
+	if state.Parent == nil {
+		return nil, errors.New("Asset " + *stateIn.AssetID + " is not contained in another asset")
+	}
+	for _, id := range []string{*stateIn.AssetID, *state.Parent} {
+		err = t.checkOwnership(overlay, id, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = t.detachFromParent(overlay, &state)
+	if err != nil {
+		return nil, err
+	}
+	return nil, overlay.commit()
+}
+
+//********************readAssetTree********************/
+
+func (t *SimpleChaincode) readAssetTree(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	// validate input data for number of args, Unmarshaling to asset state and obtain asset id
+	stateIn, err := t.validateInput(args)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := t.getAssetTree(stub, *stateIn.AssetID)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(tree)
+}
+
+/*********************************  internal: hierarchy ****************************/
+
+func (t *SimpleChaincode) getAssetTree(stub shim.ChaincodeStubInterface, assetID string) (AssetTree, error) {
+	var tree AssetTree
+	var err error
+
+	tree.State, err = t.getLiveAsset(stub, assetID)
+	if err != nil {
+		return tree, err
+	}
+	children, err := t.getChildren(stub, assetID)
+	if err != nil {
+		return tree, err
+	}
+	tree.Children = make([]AssetTree, 0, len(children))
+	for _, childID := range children {
+		child, err := t.getAssetTree(stub, childID)
+		if err != nil {
+			return tree, err
+		}
+		tree.Children = append(tree.Children, child)
+	}
+	return tree, nil
+}
+
+// propagateLocation moves everything contained in an asset to its location,
+// or clears theirs when it has none, deepest assets first so that each parent
+// rolls up fresh compliance
+func (t *SimpleChaincode) propagateLocation(stub shim.ChaincodeStubInterface, parentID string, location *Geolocation) error {
+	children, err := t.getChildren(stub, parentID)
+	if err != nil || len(children) == 0 {
+		return err
+	}
+	parent, err := t.getLiveAsset(stub, parentID)
+	if err != nil {
+		return err
+	}
+	for _, childID := range children {
+		err = t.propagateLocation(stub, childID, location)
+		if err != nil {
+			return err
+		}
+		// the event replaces the location of the child as a whole, members
+		// the container does not have are cleared
+		event := map[string]interface{}{"assetID": childID, "location": nil}
+		if location != nil {
+			event["location"] = map[string]interface{}{"latitude": location.Latitude, "longitude": location.Longitude}
+		}
+		eventJSON, err := json.Marshal(event)
+		if err != nil {
+			return errors.New("Marshal failed for location event" + fmt.Sprint(err))
+		}
+		// the child may have changed hands since it was attached, it moves
+		// along while in the custody of the same carrier as its container,
+		// otherwise only when the caller is in charge of it
+		child, err := t.getLiveAsset(stub, childID)
+		if err != nil {
+			return err
+		}
+		if child.Carrier == nil || parent.Carrier == nil || *child.Carrier != *parent.Carrier {
+			err = t.checkOwnership(stub, childID, event)
+			if err != nil {
+				return errors.New("Unable to move contained asset " + childID + ": " + err.Error())
+			}
+		}
+		state, _, err := t.nextAssetState(stub, []string{string(eventJSON)})
+		if err != nil {
+			return errors.New("Unable to move contained asset " + childID + ": " + err.Error())
+		}
+		err = t.putAssetState(stub, state)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rollUpChildren makes the asset noncompliant when any asset it contains is
+func (t *SimpleChaincode) rollUpChildren(stub shim.ChaincodeStubInterface, state *AssetState) error {
+	children, err := t.getChildren(stub, *state.AssetID)
+	if err != nil {
+		return err
+	}
+	state.NoncompliantChildren = nil
+	for _, childID := range children {
+		child, err := t.getLiveAsset(stub, childID)
+		if err != nil {
+			return err
+		}
+		if child.Compliance != nil && !*child.Compliance {
+			state.NoncompliantChildren = append(state.NoncompliantChildren, childID)
+		}
+	}
+	if len(state.NoncompliantChildren) > 0 {
+		compliant := false
+		state.Compliance = &compliant
+	}
+	return nil
+}
+
+// rollUpAncestors recalculates the compliance of every asset that contains,
+// directly or not, an asset whose compliance may have changed. Only the
+// compliance changes, the ancestors keep the transaction stamps of their
+// last event.
+func (t *SimpleChaincode) rollUpAncestors(stub shim.ChaincodeStubInterface, parentID *string) error {
+	for parentID != nil {
+		parent, err := t.getLiveAsset(stub, *parentID)
+		if err != nil {
+			return err
+		}
+		compliant, err := t.ownCompliance(stub, parent)
+		if err != nil {
+			return err
+		}
+		parent.Compliance = &compliant
+		err = t.rollUpChildren(stub, &parent)
+		if err != nil {
+			return err
+		}
+		err = t.putAssetState(stub, parent)
+		if err != nil {
+			return err
+		}
+		parentID = parent.Parent
+	}
+	return nil
+}
+
+// ownCompliance calculates the compliance of an asset from its own alerts
+func (t *SimpleChaincode) ownCompliance(stub shim.ChaincodeStubInterface, state AssetState) (bool, error) {
+	if state.Alerts == nil {
+		return true, nil
+	}
+	txnTime, err := txnTimestamp(stub)
+	if err != nil {
+		return false, errors.New("Unable to get transaction timestamp: " + fmt.Sprint(err))
+	}
+	suppressed, err := t.activeSuppressions(stub, *state.AssetID, txnTime)
+	if err != nil {
+		return false, err
+	}
+	stateMap, err := asArgsMap(state)
+	if err != nil {
+		return false, errors.New("Unable to convert state for rules: " + fmt.Sprint(err))
+	}
+	internal := state.Alerts.asAlertStatusInternal()
+	return internal.calculateContractCompliance(&stateMap, suppressed)
+}
+
+// detachFromParent removes the asset from its parent and rolls up the change
+func (t *SimpleChaincode) detachFromParent(stub shim.ChaincodeStubInterface, state *AssetState) error {
+	parentID := *state.Parent
+	children, err := t.getChildren(stub, parentID)
+	if err != nil {
+		return err
+	}
+	remaining := make([]string, 0, len(children))
+	for _, childID := range children {
+		if childID != *state.AssetID {
+			remaining = append(remaining, childID)
+		}
+	}
+	err = t.putChildren(stub, parentID, remaining)
+	if err != nil {
+		return err
+	}
+	state.Parent = nil
+	err = t.putAssetState(stub, *state)
+	if err != nil {
+		return err
+	}
+	return t.rollUpAncestors(stub, &parentID)
+}
+
+// getChildren returns the IDs of the assets directly contained in an asset
+func (t *SimpleChaincode) getChildren(stub shim.ChaincodeStubInterface, assetID string) ([]string, error) {
+	var children = make([]string, 0)
+
+	childrenBytes, err := stub.GetState(ASSETCHILDRENKEYPREFIX + assetID)
+	if err != nil {
+		return nil, errors.New("Unable to get contained assets from ledger: " + fmt.Sprint(err))
+	}
+	if len(childrenBytes) == 0 {
+		return children, nil
+	}
+	err = json.Unmarshal(childrenBytes, &children)
+	if err != nil {
+		return nil, errors.New("Unable to unmarshal contained assets obtained from ledger")
+	}
+	return children, nil
+}
+
+// putChildren stores the IDs of the assets directly contained in an asset
+func (t *SimpleChaincode) putChildren(stub shim.ChaincodeStubInterface, assetID string, children []string) error {
+	if len(children) == 0 {
+		err := stub.DelState(ASSETCHILDRENKEYPREFIX + assetID)
+		if err != nil {
+			return errors.New("DELSTATE failed! : " + fmt.Sprint(err))
+		}
+		return nil
+	}
+	sort.Strings(children)
+	childrenJSON, err := json.Marshal(children)
+	if err != nil {
+		return errors.New("Marshal failed for contained assets" + fmt.Sprint(err))
+	}
+	err = stub.PutState(ASSETCHILDRENKEYPREFIX+assetID, childrenJSON)
+	if err != nil {
+		return errors.New("PUT ledger state failed: " + fmt.Sprint(err))
+	}
+	return nil
+}
+
+// checkDetached fails when the asset contains or is contained in another
+func (t *SimpleChaincode) checkDetached(stub shim.ChaincodeStubInterface, assetID string) error {
+	var state AssetState
+
+	assetBytes, err := getAssetBytes(stub, assetID)
+	if err != nil {
+		return err
+	}
+	if len(assetBytes) > 0 {
+		err = json.Unmarshal(assetBytes, &state)
+		if err != nil {
+			return errors.New("Unable to unmarshal state data obtained from ledger")
+		}
+		if state.Parent != nil {
+			return errors.New("Asset " + assetID + " is contained in " + *state.Parent + ", detach it first")
+		}
+	}
+	children, err := t.getChildren(stub, assetID)
+	if err != nil {
+		return err
+	}
+	if len(children) > 0 {
+		return errors.New("Asset " + assetID + " contains other assets, detach them first")
+	}
+	return nil
+}
+
+// getLiveAsset returns the state of an asset that exists and is not deleted
+func (t *SimpleChaincode) getLiveAsset(stub shim.ChaincodeStubInterface, assetID string) (AssetState, error) {
+	var state AssetState
+
+	assetBytes, err := getAssetBytes(stub, assetID)
+	if err != nil {
+		return state, err
+	}
+	if len(assetBytes) == 0 {
+		return state, errors.New("Asset " + assetID + " does not exist!")
+	}
+	deleted, err := t.isDeleted(stub, assetID)
+	if err != nil {
+		return state, err
+	}
+	if deleted {
+		return state, errors.New("Asset " + assetID + " does not exist!")
+	}
+	err = json.Unmarshal(assetBytes, &state)
+	if err != nil {
+		return state, errors.New("Unable to unmarshal state data obtained from ledger")
+	}
+	return state, nil
+}
+
+// putAssetState writes the state of an asset as its next version
+func (t *SimpleChaincode) putAssetState(stub shim.ChaincodeStubInterface, state AssetState) error {
+	version := assetVersion(state) + 1
+	state.Version = &version
+	schemaVersion := MYVERSION
+	state.SchemaVersion = &schemaVersion
+	stateJSON, err := json.Marshal(state)
+	if err != nil {
+		return errors.New("Marshal failed for asset state" + fmt.Sprint(err))
+	}
+	err = stub.PutState(*state.AssetID, stateJSON)
+	if err != nil {
+		return errors.New("PUT ledger state failed: " + fmt.Sprint(err))
+	}
+	return nil
+}
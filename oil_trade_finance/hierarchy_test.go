@@ -0,0 +1,181 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// newFleetContract returns a contract with vessel V1 carrying reefer R1,
+// which holds tank T1
+func newFleetContract(t *testing.T) *mockStub {
+	m := newTestContract(t)
+	m.mustInvoke("createAsset", `{"assetID":"V1","assetType":"Vessel","location":{"latitude":51.9,"longitude":4.1}}`)
+	m.mustInvoke("createAsset", `{"assetID":"R1","assetType":"ReeferContainer","maxTemperature":4}`)
+	m.mustInvoke("createAsset", `{"assetID":"T1","assetType":"CrudeTank","maxTemperature":20}`)
+	m.mustInvoke("attachAsset", `{"assetID":"T1","parent":"R1"}`)
+	m.mustInvoke("attachAsset", `{"assetID":"R1","parent":"V1"}`)
+	return m
+}
+
+func TestAssetTree(t *testing.T) {
+	var tree AssetTree
+
+	m := newFleetContract(t)
+	json.Unmarshal(m.mustQuery("readAssetTree", `{"assetID":"V1"}`), &tree)
+	if *tree.State.AssetID != "V1" || len(tree.Children) != 1 || *tree.Children[0].State.AssetID != "R1" ||
+		len(tree.Children[0].Children) != 1 || *tree.Children[0].Children[0].State.AssetID != "T1" ||
+		len(tree.Children[0].Children[0].Children) != 0 {
+		t.Fatalf("unexpected tree %+v", tree)
+	}
+	if *m.asset("T1").Parent != "R1" || *m.asset("R1").Parent != "V1" || m.asset("V1").Parent != nil {
+		t.Fatal("the parents are not recorded")
+	}
+}
+
+func TestAttachAssetRefused(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    string
+		wantErr string
+	}{
+		{"itself", `{"assetID":"V1","parent":"V1"}`, "Asset V1 cannot be contained in itself"},
+		{"its descendant", `{"assetID":"V1","parent":"T1"}`, "Asset V1 cannot be contained in itself"},
+		{"no parent", `{"assetID":"T1"}`, "args[0].parent: is required"},
+		{"unknown parent", `{"assetID":"T1","parent":"V9"}`, "Asset V9 does not exist!"},
+		{"unknown asset", `{"assetID":"T9","parent":"V1"}`, "Asset T9 does not exist!"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newFleetContract(t).invoke("attachAsset", tt.args)
+			wantError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestLocationPropagates(t *testing.T) {
+	m := newFleetContract(t)
+	m.mustInvoke("updateAsset", `{"assetID":"V1","location":{"latitude":1.3,"longitude":103.8}}`)
+	for _, id := range []string{"R1", "T1"} {
+		location := m.asset(id).Location
+		if location == nil || *location.Latitude != 1.3 || *location.Longitude != 103.8 {
+			t.Fatalf("%s is at %+v, expected the location of its vessel", id, location)
+		}
+	}
+	m.mustInvoke("updateAsset", `{"assetID":"V1","location":null}`)
+	if m.asset("T1").Location != nil {
+		t.Fatal("the location of a contained asset was not cleared with its vessel's")
+	}
+}
+
+func TestComplianceRollsUp(t *testing.T) {
+	m := newFleetContract(t)
+	m.mustInvoke("updateAsset", `{"assetID":"T1","sealBroken":true}`)
+	steps := []struct {
+		id                  string
+		wantCompliant       bool
+		wantNoncompliantIDs []string
+	}{
+		{"T1", false, nil},
+		{"R1", false, []string{"T1"}},
+		{"V1", false, []string{"R1"}},
+	}
+	for _, step := range steps {
+		state := m.asset(step.id)
+		if *state.Compliance != step.wantCompliant || !reflect.DeepEqual(state.NoncompliantChildren, step.wantNoncompliantIDs) {
+			t.Fatalf("%s compliant %v with noncompliant children %v, expected %v with %v",
+				step.id, *state.Compliance, state.NoncompliantChildren, step.wantCompliant, step.wantNoncompliantIDs)
+		}
+	}
+	// the vessel does not keep the alerts of its cargo, only the roll up
+	if activeAlerts(m.asset("V1")) != nil {
+		t.Fatal("the alerts of a contained asset were copied to the vessel")
+	}
+
+	// taking the tank out of the reefer makes the reefer, and the vessel,
+	// compliant again
+	m.mustInvoke("detachAsset", `{"assetID":"T1"}`)
+	for _, id := range []string{"R1", "V1"} {
+		state := m.asset(id)
+		if !*state.Compliance || state.NoncompliantChildren != nil {
+			t.Fatalf("%s is still noncompliant after the tank was detached: %+v", id, state)
+		}
+	}
+	_, err := m.invoke("detachAsset", `{"assetID":"T1"}`)
+	wantError(t, err, "Asset T1 is not contained in another asset")
+}
+
+func TestAttachMovesBetweenContainers(t *testing.T) {
+	var tree AssetTree
+
+	m := newFleetContract(t)
+	m.mustInvoke("createAsset", `{"assetID":"R2","assetType":"ReeferContainer"}`)
+	m.mustInvoke("attachAsset", `{"assetID":"T1","parent":"R2"}`)
+	json.Unmarshal(m.mustQuery("readAssetTree", `{"assetID":"R1"}`), &tree)
+	if len(tree.Children) != 0 {
+		t.Fatalf("T1 is still in R1 after moving to R2: %+v", tree)
+	}
+	if *m.asset("T1").Parent != "R2" {
+		t.Fatal("T1 did not move to R2")
+	}
+	// attaching to the current parent changes nothing
+	before := string(m.state["T1"])
+	m.mustInvoke("attachAsset", `{"assetID":"T1","parent":"R2"}`)
+	if string(m.state["T1"]) != before {
+		t.Fatal("attaching an asset to its own parent wrote it again")
+	}
+}
+
+func TestCheckDetached(t *testing.T) {
+	m := newFleetContract(t)
+	_, err := m.invoke("deleteAsset", `{"assetID":"R1"}`)
+	wantError(t, err, "Asset R1 is contained in V1, detach it first")
+	m.mustInvoke("detachAsset", `{"assetID":"R1"}`)
+	_, err = m.invoke("deleteAsset", `{"assetID":"R1"}`)
+	wantError(t, err, "Asset R1 contains other assets, detach them first")
+}
+
+func TestHierarchyOwnership(t *testing.T) {
+	m := newTestContract(t)
+	m.mustInvoke("createAsset", `{"assetID":"V1","assetType":"Vessel","carrier":"alpha"}`)
+	m.mustInvoke("createAsset", `{"assetID":"V2","assetType":"Vessel","carrier":"beta"}`)
+	m.mustInvoke("createAsset", `{"assetID":"T1","assetType":"CrudeTank","carrier":"alpha"}`)
+	m.mustInvoke("createAsset", `{"assetID":"T2","assetType":"CrudeTank","carrier":"alpha"}`)
+	m.mustInvoke("registerDevice", `{"assetID":"V1","device":"gps-1"}`)
+
+	// a carrier may not take in the asset of another
+	_, err := m.as("beta", CARRIERROLE).invoke("attachAsset", `{"assetID":"T1","parent":"V2"}`)
+	wantError(t, err, "Permission denied: only the carrier alpha of asset T1")
+	_, err = m.as("alpha", CARRIERROLE).invoke("attachAsset", `{"assetID":"T1","parent":"V2"}`)
+	wantError(t, err, "Permission denied: only the carrier beta of asset V2")
+	m.mustInvoke("attachAsset", `{"assetID":"T1","parent":"V1"}`)
+	m.mustInvoke("attachAsset", `{"assetID":"T2","parent":"V1"}`)
+	_, err = m.as("beta", CARRIERROLE).invoke("detachAsset", `{"assetID":"T1"}`)
+	wantError(t, err, "Permission denied: only the carrier alpha of asset T1")
+
+	// the device of the vessel moves what the same carrier loaded on it
+	m.as("gps-1", DEVICEROLE).mustInvoke("updateAsset", `{"assetID":"V1","location":{"latitude":1,"longitude":2}}`)
+	if *m.asset("T1").Location.Latitude != 1 {
+		t.Fatal("the tank did not move with its vessel")
+	}
+
+	// a contained asset handed over to another carrier is no longer moved
+	// by the device of the vessel
+	m.as("alpha", CARRIERROLE).mustInvoke("proposeHandoff", `{"assetID":"T2","toCarrier":"beta"}`)
+	m.as("beta", CARRIERROLE).mustInvoke("acceptHandoff", `{"assetID":"T2"}`)
+	_, err = m.as("gps-1", DEVICEROLE).invoke("updateAsset", `{"assetID":"V1","location":{"latitude":3,"longitude":4}}`)
+	wantError(t, err, "Unable to move contained asset T2: Permission denied: only the carrier beta of asset T2")
+	if *m.asset("T2").Location.Latitude != 1 {
+		t.Fatal("the asset of another carrier was moved")
+	}
+}
@@ -0,0 +1,204 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+// Latched alerts
+//
+// An alert marked in AlertsLatched is raised by the rules like any other, but
+// the rules never clear it. Only an inspector can, through clearLatchedAlert,
+// and every clearance is kept on the ledger with its reason.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// LATCHEDCLEARANCESKEYPREFIX prefixes the asset ID to store its latched alert clearances
+const LATCHEDCLEARANCESKEYPREFIX string = "LatchedAlertClearances:"
+
+// LatchedAlertClearance records an inspector clearing a latched alert
+type LatchedAlertClearance struct {
+	AssetID      string `json:"assetID"`
+	Alert        string `json:"alert"`
+	Reason       string `json:"reason"`
+	ClearedBy    string `json:"clearedBy"`    // inspector that cleared the alert
+	TxnID        string `json:"txnuuid"`      // transaction that cleared the alert
+	TxnTimestamp string `json:"txntimestamp"` // transaction timestamp of the clearance
+}
+
+//******************** clearLatchedAlert ********************/
+
+func (t *SimpleChaincode) clearLatchedAlert(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	var clearance LatchedAlertClearance
+	var state AssetState
+	var err error
+
+	// validate input data for number of args, Unmarshaling to asset state and obtain asset id
+	stateIn, err := t.validateInput(args)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal([]byte(args[0]), &clearance)
+	if err != nil {
+		return nil, errors.New("Unable to unmarshal clearance JSON data")
+	}
+	clearance.AssetID = *stateIn.AssetID
+	alert, found := AlertsValue[clearance.Alert]
+	if !found {
+		return nil, errors.New("Unknown alert: " + clearance.Alert)
+	}
+	if !AlertsLatched[alert] {
+		return nil, errors.New("Alert " + clearance.Alert + " is not latched")
+	}
+	if strings.TrimSpace(clearance.Reason) == "" {
+		return nil, errors.New("A reason is required to clear a latched alert")
+	}
+
+	assetBytes, err := getAssetBytes(stub, clearance.AssetID)
+	if err != nil || len(assetBytes) == 0 {
+		return nil, errors.New("Asset does not exist!")
+	}
+	deleted, err := t.isDeleted(stub, clearance.AssetID)
+	if err != nil {
+		return nil, err
+	}
+	if deleted {
+		return nil, errors.New("Asset does not exist!")
+	}
+	err = json.Unmarshal(assetBytes, &state)
+	if err != nil {
+		return nil, errors.New("Unable to unmarshal state data obtained from ledger")
+	}
+	if state.Alerts == nil {
+		return nil, errors.New("Alert " + clearance.Alert + " is not active")
+	}
+	internal := state.Alerts.asAlertStatusInternal()
+	if !internal.Active[alert] {
+		return nil, errors.New("Alert " + clearance.Alert + " is not active")
+	}
+
+	// this transaction produces a new state in which only the latched alert
+	// changed, so it is the only transition recorded
+	internal.clearRaisedAndClearedStatus()
+	internal.Active[alert] = false
+	internal.Cleared[alert] = true
+
+	txnTime, err := txnTimestamp(stub)
+	if err != nil {
+		return nil, errors.New("Unable to get transaction timestamp: " + fmt.Sprint(err))
+	}
+	txnID := stub.GetTxID()
+	suppressed, err := t.activeSuppressions(stub, clearance.AssetID, txnTime)
+	if err != nil {
+		return nil, err
+	}
+	stateMap, err := asArgsMap(state)
+	if err != nil {
+		return nil, errors.New("Unable to convert state for rules: " + fmt.Sprint(err))
+	}
+	compliant, err := internal.calculateContractCompliance(&stateMap, suppressed)
+	if err != nil {
+		return nil, err
+	}
+	alerts := internal.asAlertStatus()
+	state.Alerts = &alerts
+	state.Compliance = &compliant
+	state.Timestamp = nil // no device reading in this state
+	state.TxnTimestamp = &txnTime
+	state.TxnID = &txnID
+
+	// containing assets see the new compliance within this transaction
+	overlay := newOverlayStub(stub)
+	err = t.rollUpChildren(overlay, &state)
+	if err != nil {
+		return nil, err
+	}
+	err = t.putAssetState(overlay, state)
+	if err != nil {
+		return nil, err
+	}
+	err = t.rollUpAncestors(overlay, state.Parent)
+	if err != nil {
+		return nil, err
+	}
+	err = overlay.commit()
+	if err != nil {
+		return nil, err
+	}
+
+	// audit the clearance
+	clearance.ClearedBy, err = callerIdentity(stub)
+	if err != nil {
+		return nil, err
+	}
+	clearance.TxnID = txnID
+	clearance.TxnTimestamp = txnTime
+	clearances, err := t.getLatchedAlertClearances(stub, clearance.AssetID)
+	if err != nil {
+		return nil, err
+	}
+	clearances = append(clearances, clearance)
+	clearancesJSON, err := json.Marshal(clearances)
+	if err != nil {
+		return nil, errors.New("Marshal failed for latched alert clearances" + fmt.Sprint(err))
+	}
+	err = stub.PutState(LATCHEDCLEARANCESKEYPREFIX+clearance.AssetID, clearancesJSON)
+	if err != nil {
+		return nil, errors.New("PUT ledger state failed: " + fmt.Sprint(err))
+	}
+
+	clearanceJSON, err := json.Marshal(clearance)
+	if err != nil {
+		return nil, errors.New("Marshal failed for latched alert clearance" + fmt.Sprint(err))
+	}
+	err = stub.SetEvent("latchedAlertCleared", clearanceJSON)
+	if err != nil {
+		return nil, errors.New("Unable to set latchedAlertCleared event: " + fmt.Sprint(err))
+	}
+	return nil, nil
+}
+
+//********************readLatchedAlertClearances********************/
+
+func (t *SimpleChaincode) readLatchedAlertClearances(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	// validate input data for number of args, Unmarshaling to asset state and obtain asset id
+	stateIn, err := t.validateInput(args)
+	if err != nil {
+		return nil, err
+	}
+	clearances, err := t.getLatchedAlertClearances(stub, *stateIn.AssetID)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(clearances)
+}
+
+// getLatchedAlertClearances returns every latched alert clearance recorded for the asset
+func (t *SimpleChaincode) getLatchedAlertClearances(stub shim.ChaincodeStubInterface, assetID string) ([]LatchedAlertClearance, error) {
+	var clearances = make([]LatchedAlertClearance, 0)
+
+	clearancesBytes, err := stub.GetState(LATCHEDCLEARANCESKEYPREFIX + assetID)
+	if err != nil {
+		return nil, errors.New("Unable to get latched alert clearances from ledger: " + fmt.Sprint(err))
+	}
+	if len(clearancesBytes) == 0 {
+		return clearances, nil
+	}
+	err = json.Unmarshal(clearancesBytes, &clearances)
+	if err != nil {
+		return nil, errors.New("Unable to unmarshal latched alert clearances obtained from ledger")
+	}
+	return clearances, nil
+}
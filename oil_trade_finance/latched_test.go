@@ -0,0 +1,76 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestClearLatchedAlert(t *testing.T) {
+	m := newFleetContract(t)
+	m.mustInvoke("updateAsset", `{"assetID":"T1","sealBroken":true}`)
+	m.mustInvoke("updateAsset", `{"assetID":"T1","sealBroken":false}`)
+	if got := activeAlerts(m.asset("T1")); !reflect.DeepEqual(got, []string{"SEALTAMPER"}) {
+		t.Fatalf("active alerts %v, expected the latched SEALTAMPER", got)
+	}
+
+	m.as("inspector", INSPECTORROLE).mustInvoke("clearLatchedAlert", `{"assetID":"T1","alert":"SEALTAMPER","reason":"seal replaced at Rotterdam"}`)
+	event := m.lastEvent()
+	if event.Name != "latchedAlertCleared" {
+		t.Fatalf("event %s, expected latchedAlertCleared", event.Name)
+	}
+	state := m.asset("T1")
+	if activeAlerts(state) != nil || !reflect.DeepEqual(alertNames(state.Alerts.Cleared), []string{"SEALTAMPER"}) ||
+		!*state.Compliance || state.Timestamp != nil || *state.TxnID != event.TxID {
+		t.Fatalf("unexpected state after the clearance %+v", state)
+	}
+	// the containers take in the compliance of the tank
+	if !*m.asset("R1").Compliance || !*m.asset("V1").Compliance {
+		t.Fatal("the clearance did not roll up to the containers")
+	}
+
+	var clearances []LatchedAlertClearance
+	json.Unmarshal(m.mustQuery("readLatchedAlertClearances", `{"assetID":"T1"}`), &clearances)
+	if len(clearances) != 1 || clearances[0].ClearedBy != "inspector" || clearances[0].Reason != "seal replaced at Rotterdam" ||
+		clearances[0].TxnID != event.TxID {
+		t.Fatalf("unexpected clearances %+v", clearances)
+	}
+
+	// the seal is still intact at the next reading, the alert stays cleared
+	m.as("admin", ADMINROLE).mustInvoke("updateAsset", `{"assetID":"T1","maxTemperature":21}`)
+	if activeAlerts(m.asset("T1")) != nil {
+		t.Fatal("the cleared latched alert came back without a broken seal")
+	}
+}
+
+func TestClearLatchedAlertRefused(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    string
+		wantErr string
+	}{
+		{"not latched", `{"assetID":"T1","alert":"OVERTEMP","reason":"r"}`, "args[0].alert: OVERTEMP is not one of SEALTAMPER"},
+		{"unknown alert", `{"assetID":"T1","alert":"RUST","reason":"r"}`, "RUST"},
+		{"not active", `{"assetID":"R1","alert":"SEALTAMPER","reason":"r"}`, "Alert SEALTAMPER is not active"},
+		{"no reason", `{"assetID":"T1","alert":"SEALTAMPER","reason":" "}`, "A reason is required to clear a latched alert"},
+		{"unknown asset", `{"assetID":"T9","alert":"SEALTAMPER","reason":"r"}`, "Asset does not exist!"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newFleetContract(t)
+			m.mustInvoke("updateAsset", `{"assetID":"T1","sealBroken":true,"maxTemperature":70}`)
+			_, err := m.as("inspector", INSPECTORROLE).invoke("clearLatchedAlert", tt.args)
+			wantError(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,364 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+// Contract lifecycle
+//
+// The status in the contract state decides what the contract still does.
+// An active contract serves everything. A paused contract is frozen for
+// incident response: it refuses every invoke and every query but
+// readContractState. A read-only contract serves queries and refuses
+// invokes. A retired contract is read-only for good. Admins move the
+// contract between statuses with the lifecycle invokes, which are served
+// whatever the status, and each change records who made it and why.
+//
+// Init runs again whenever the chaincode is upgraded, so it keeps the
+// contract and trade states it finds on the ledger. A contract deployed by an older
+// version is upgraded one step at a time along contractUpgrades, a newer
+// one is refused.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// STATUSACTIVE is the status of a contract that serves every function
+const STATUSACTIVE uint8 = 0
+
+// STATUSPAUSED is the status of a contract frozen during an incident
+const STATUSPAUSED uint8 = 1
+
+// STATUSREADONLY is the status of a contract that serves queries only
+const STATUSREADONLY uint8 = 2
+
+// STATUSRETIRED is the status of a contract that serves queries only, for good
+const STATUSRETIRED uint8 = 3
+
+// ContractStatusName names the contract statuses in messages
+var ContractStatusName = map[uint8]string{
+	STATUSACTIVE:   "active",
+	STATUSPAUSED:   "paused",
+	STATUSREADONLY: "read-only",
+	STATUSRETIRED:  "retired",
+}
+
+// contractLifecycleFunctions are served whatever the status of the contract
+var contractLifecycleFunctions = map[string]bool{
+	"pauseContract":  true,
+	"resumeContract": true,
+	"retireContract": true,
+}
+
+// contractUpgrade converts the records of one contract version to the next,
+// apply is nil when the next version reads them as they are
+type contractUpgrade struct {
+	from  string
+	to    string
+	apply func(stub shim.ChaincodeStubInterface) error
+}
+
+// contractUpgrades is the upgrade path from the first deployed version to
+// MYVERSION, in order
+var contractUpgrades = []contractUpgrade{
+	// 1.1 brought asset versions, roles, ownership and the lifecycle, the
+	// asset states of 1.0 are left to assetMigrations
+	{"1.0", "1.1", nil},
+}
+
+// StatusChange is the argument of the lifecycle invokes
+type StatusChange struct {
+	Reason   string `json:"reason"`
+	ReadOnly bool   `json:"readOnly,omitempty"` // pauseContract only, keep serving queries
+}
+
+//******************** pauseContract ********************/
+
+func (t *SimpleChaincode) pauseContract(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	change, err := readStatusChange(args)
+	if err != nil {
+		return nil, err
+	}
+	status := STATUSPAUSED
+	if change.ReadOnly {
+		status = STATUSREADONLY
+	}
+	return nil, t.changeContractStatus(stub, change, status, STATUSACTIVE, STATUSPAUSED, STATUSREADONLY)
+}
+
+//******************** resumeContract ********************/
+
+func (t *SimpleChaincode) resumeContract(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	change, err := readStatusChange(args)
+	if err != nil {
+		return nil, err
+	}
+	return nil, t.changeContractStatus(stub, change, STATUSACTIVE, STATUSPAUSED, STATUSREADONLY)
+}
+
+//******************** retireContract ********************/
+
+func (t *SimpleChaincode) retireContract(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	change, err := readStatusChange(args)
+	if err != nil {
+		return nil, err
+	}
+	return nil, t.changeContractStatus(stub, change, STATUSRETIRED, STATUSACTIVE, STATUSPAUSED, STATUSREADONLY)
+}
+
+/*********************************  internal: lifecycle ****************************/
+
+// checkContractStatus refuses a function that the status of the contract
+// does not allow, write tells an invoke from a query
+func checkContractStatus(stub shim.ChaincodeStubInterface, function string, write bool) error {
+	if contractLifecycleFunctions[function] {
+		return nil
+	}
+	state, found, err := getContractState(stub)
+	if err != nil || !found {
+		// not initialized, there is no status to enforce yet
+		return err
+	}
+	switch state.Status {
+	case STATUSACTIVE:
+		return nil
+	case STATUSPAUSED:
+		if function == "readContractState" {
+			return nil
+		}
+	case STATUSREADONLY, STATUSRETIRED:
+		if !write {
+			return nil
+		}
+	}
+	return errors.New("The contract is " + contractStatusName(state.Status) + ", " + function + " is not allowed")
+}
+
+// changeContractStatus moves the contract to a status from one of the
+// statuses given and records the change
+func (t *SimpleChaincode) changeContractStatus(stub shim.ChaincodeStubInterface, change StatusChange, status uint8, from ...uint8) error {
+	state, found, err := getContractState(stub)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.New("The contract is not initialized")
+	}
+	if state.Status == status {
+		return errors.New("The contract is already " + contractStatusName(status))
+	}
+	allowed := false
+	for _, s := range from {
+		allowed = allowed || state.Status == s
+	}
+	if !allowed {
+		return errors.New("The contract is " + contractStatusName(state.Status) + ", it cannot be made " + contractStatusName(status))
+	}
+
+	state.Status = status
+	state.StatusReason = change.Reason
+	state.StatusChangedBy, err = callerIdentity(stub)
+	if err != nil {
+		return err
+	}
+	state.StatusTxnTimestamp, err = txnTimestamp(stub)
+	if err != nil {
+		return errors.New("Unable to get transaction timestamp: " + fmt.Sprint(err))
+	}
+	contractStateJSON, err := putContractState(stub, state)
+	if err != nil {
+		return err
+	}
+	err = stub.SetEvent("contractStatusChanged", contractStateJSON)
+	if err != nil {
+		return errors.New("Unable to set contractStatusChanged event: " + fmt.Sprint(err))
+	}
+	return nil
+}
+
+// initContractState writes the contract state on first deployment, keeps it
+// when Init runs again for the same version and upgrades it when deployed
+// by an older version
+func (t *SimpleChaincode) initContractState(stub shim.ChaincodeStubInterface) error {
+	state, found, err := getContractState(stub)
+	if err != nil {
+		return err
+	}
+	if !found {
+		_, err = putContractState(stub, ContractState{Version: MYVERSION, Status: DEFAULTSTATUS})
+		return err
+	}
+	order, err := compareVersions(state.Version, MYVERSION)
+	if err != nil {
+		return err
+	}
+	if order > 0 {
+		return errors.New("The ledger holds contract version " + state.Version + ", version " + MYVERSION + " cannot downgrade it")
+	}
+	if order == 0 {
+		// same version, status and its history are kept
+		return nil
+	}
+	return t.upgradeContract(stub, state)
+}
+
+// upgradeContract walks the upgrade path from the stored version to MYVERSION,
+// keeping the status of the contract
+func (t *SimpleChaincode) upgradeContract(stub shim.ChaincodeStubInterface, state ContractState) error {
+	version := state.Version
+	for version != MYVERSION {
+		var step *contractUpgrade
+		for i := range contractUpgrades {
+			if contractUpgrades[i].from == version {
+				step = &contractUpgrades[i]
+				break
+			}
+		}
+		if step == nil {
+			return errors.New("No upgrade path from contract version " + version + " to " + MYVERSION)
+		}
+		if step.apply != nil {
+			err := step.apply(stub)
+			if err != nil {
+				return errors.New("Upgrade from contract version " + step.from + " to " + step.to + " failed: " + fmt.Sprint(err))
+			}
+		}
+		version = step.to
+	}
+	state.UpgradedFrom = state.Version
+	state.Version = MYVERSION
+	contractStateJSON, err := putContractState(stub, state)
+	if err != nil {
+		return err
+	}
+	err = stub.SetEvent("contractUpgraded", contractStateJSON)
+	if err != nil {
+		return errors.New("Unable to set contractUpgraded event: " + fmt.Sprint(err))
+	}
+	return nil
+}
+
+// initTradeState writes the trade state on first deployment and otherwise
+// makes sure the contract is initialized for the trade it already holds
+func initTradeState(stub shim.ChaincodeStubInterface, trade TradeState) error {
+	var stored TradeState
+
+	tradeStateBytes, err := stub.GetState(TRADESTATEKEY)
+	if err != nil {
+		return errors.New("Unable to get trade state from ledger: " + fmt.Sprint(err))
+	}
+	if len(tradeStateBytes) != 0 {
+		err = json.Unmarshal(tradeStateBytes, &stored)
+		if err != nil {
+			return errors.New("Unable to unmarshal trade state obtained from ledger")
+		}
+		if stored.TradeID != trade.TradeID {
+			return errors.New("The ledger holds trade " + stored.TradeID + ", it cannot be initialized for trade " + trade.TradeID)
+		}
+		return nil
+	}
+	tradeStateJSON, err := json.Marshal(trade)
+	if err != nil {
+		return errors.New("Marshal failed for trade state" + fmt.Sprint(err))
+	}
+	err = stub.PutState(TRADESTATEKEY, tradeStateJSON)
+	if err != nil {
+		return errors.New("Trade state failed PUT to ledger: " + fmt.Sprint(err))
+	}
+	return nil
+}
+
+// getContractState returns the contract state and false before Init
+func getContractState(stub shim.ChaincodeStubInterface) (ContractState, bool, error) {
+	var state ContractState
+
+	contractStateBytes, err := stub.GetState(CONTRACTSTATEKEY)
+	if err != nil {
+		return state, false, errors.New("Unable to get contract state from ledger: " + fmt.Sprint(err))
+	}
+	if len(contractStateBytes) == 0 {
+		return state, false, nil
+	}
+	err = json.Unmarshal(contractStateBytes, &state)
+	if err != nil {
+		return state, false, errors.New("Unable to unmarshal contract state obtained from ledger")
+	}
+	return state, true, nil
+}
+
+// putContractState writes the contract state and returns its JSON
+func putContractState(stub shim.ChaincodeStubInterface, state ContractState) ([]byte, error) {
+	contractStateJSON, err := json.Marshal(state)
+	if err != nil {
+		return nil, errors.New("Marshal failed for contract state" + fmt.Sprint(err))
+	}
+	err = stub.PutState(CONTRACTSTATEKEY, contractStateJSON)
+	if err != nil {
+		return nil, errors.New("Contract state failed PUT to ledger: " + fmt.Sprint(err))
+	}
+	return contractStateJSON, nil
+}
+
+// compareVersions orders two dotted contract versions, a missing part
+// counts as 0
+func compareVersions(a string, b string) (int, error) {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		var err error
+		if i < len(as) {
+			if x, err = strconv.Atoi(as[i]); err != nil {
+				return 0, errors.New("Invalid contract version: " + a)
+			}
+		}
+		if i < len(bs) {
+			if y, err = strconv.Atoi(bs[i]); err != nil {
+				return 0, errors.New("Invalid contract version: " + b)
+			}
+		}
+		if x != y {
+			if x < y {
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+// readStatusChange reads the argument of a lifecycle invoke
+func readStatusChange(args []string) (StatusChange, error) {
+	var change StatusChange
+
+	if len(args) != 1 {
+		return change, errors.New("Incorrect number of arguments. Expecting a JSON string with the reason for the change")
+	}
+	err := json.Unmarshal([]byte(args[0]), &change)
+	if err != nil {
+		return change, errors.New("Unable to unmarshal status change JSON data")
+	}
+	change.Reason = strings.TrimSpace(change.Reason)
+	if change.Reason == "" {
+		return change, errors.New("The reason for the change is mandatory")
+	}
+	return change, nil
+}
+
+func contractStatusName(status uint8) string {
+	if name, found := ContractStatusName[status]; found {
+		return name
+	}
+	return fmt.Sprint(status)
+}
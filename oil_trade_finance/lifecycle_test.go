@@ -0,0 +1,165 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestContractStatusTransitions(t *testing.T) {
+	steps := []struct {
+		function   string
+		args       string
+		wantErr    string
+		wantStatus uint8
+	}{
+		{"resumeContract", `{"reason":"r"}`, "The contract is already active", STATUSACTIVE},
+		{"pauseContract", `{"reason":" "}`, "The reason for the change is mandatory", STATUSACTIVE},
+		{"pauseContract", `{"reason":"incident 42"}`, "", STATUSPAUSED},
+		{"pauseContract", `{"reason":"incident 42"}`, "The contract is already paused", STATUSPAUSED},
+		{"pauseContract", `{"reason":"queries are safe","readOnly":true}`, "", STATUSREADONLY},
+		{"resumeContract", `{"reason":"incident closed"}`, "", STATUSACTIVE},
+		{"retireContract", `{"reason":"trade settled"}`, "", STATUSRETIRED},
+		{"resumeContract", `{"reason":"r"}`, "The contract is retired, it cannot be made active", STATUSRETIRED},
+		{"pauseContract", `{"reason":"r"}`, "The contract is retired, it cannot be made paused", STATUSRETIRED},
+	}
+
+	m := newTestContract(t)
+	for i, step := range steps {
+		_, err := m.invoke(step.function, step.args)
+		wantError(t, err, step.wantErr)
+		var state ContractState
+		json.Unmarshal(m.mustQuery("readContractState"), &state)
+		if state.Status != step.wantStatus {
+			t.Fatalf("step %d: status %s, expected %s", i, contractStatusName(state.Status), contractStatusName(step.wantStatus))
+		}
+		if step.wantErr == "" {
+			event := m.lastEvent()
+			if event.Name != "contractStatusChanged" || state.StatusChangedBy != "admin" ||
+				state.StatusTxnTimestamp != m.txTime.Add(-mockTxInterval).Format(time.RFC3339Nano) {
+				t.Fatalf("step %d: unexpected event %s or state %+v", i, event.Name, state)
+			}
+		}
+	}
+}
+
+func TestContractStatusGating(t *testing.T) {
+	tests := []struct {
+		name     string
+		pause    string
+		function string
+		args     string
+		wantErr  string
+	}{
+		{"paused refuses invokes", `{"reason":"r"}`, "updateAsset", `{"assetID":"T1","maxTemperature":1}`, "The contract is paused, updateAsset is not allowed"},
+		{"paused refuses queries", `{"reason":"r"}`, "readAsset", `{"assetID":"T1"}`, "The contract is paused, readAsset is not allowed"},
+		{"paused reads its own state", `{"reason":"r"}`, "readContractState", "", ""},
+		{"read-only refuses invokes", `{"reason":"r","readOnly":true}`, "deleteAsset", `{"assetID":"T1"}`, "The contract is read-only, deleteAsset is not allowed"},
+		{"read-only serves queries", `{"reason":"r","readOnly":true}`, "readAsset", `{"assetID":"T1"}`, ""},
+		{"lifecycle is always served", `{"reason":"r"}`, "resumeContract", `{"reason":"r"}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestContract(t)
+			m.mustInvoke("createAsset", `{"assetID":"T1","assetType":"CrudeTank"}`)
+			m.mustInvoke("pauseContract", tt.pause)
+			var args []string
+			if tt.args != "" {
+				args = append(args, tt.args)
+			}
+			_, err := m.transact(false, false, tt.function, args)
+			wantError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestReinitKeepsState(t *testing.T) {
+	m := newTestContract(t)
+	m.mustInvoke("createAsset", `{"assetID":"T1","assetType":"CrudeTank"}`)
+	m.mustInvoke("pauseContract", `{"reason":"incident 42"}`)
+	m.mustInit(`{"version":"`+MYVERSION+`"}`, `{"tradeID":"`+TRADEID+`"}`)
+
+	var state ContractState
+	json.Unmarshal(m.mustQuery("readContractState"), &state)
+	if state.Status != STATUSPAUSED || state.StatusReason != "incident 42" {
+		t.Fatalf("the status was not kept by a re-init: %+v", state)
+	}
+	if _, found := m.state["T1"]; !found {
+		t.Fatal("the assets were not kept by a re-init")
+	}
+}
+
+func TestInitUpgrade(t *testing.T) {
+	tests := []struct {
+		name             string
+		stored           string
+		storedTrade      string
+		wantErr          string
+		wantUpgradedFrom string
+	}{
+		{"from 1.0", `{"version":"1.0","status":1}`, TRADEID, "", "1.0"},
+		{"same version", `{"version":"` + MYVERSION + `"}`, TRADEID, "", ""},
+		{"downgrade", `{"version":"1.2"}`, TRADEID, "The ledger holds contract version 1.2, version 1.1 cannot downgrade it", ""},
+		{"unknown path", `{"version":"0.9"}`, TRADEID, "No upgrade path from contract version 0.9 to 1.1", ""},
+		{"invalid version", `{"version":"one"}`, TRADEID, "Invalid contract version: one", ""},
+		{"other trade", `{"version":"1.0"}`, "TRADE-2", "The ledger holds trade TRADE-2, it cannot be initialized for trade " + TRADEID, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMockStub(t)
+			m.state[CONTRACTSTATEKEY] = []byte(tt.stored)
+			m.state[TRADESTATEKEY] = []byte(`{"tradeID":"` + tt.storedTrade + `"}`)
+			err := m.init(`{"version":"`+MYVERSION+`"}`, `{"tradeID":"`+TRADEID+`"}`)
+			wantError(t, err, tt.wantErr)
+			if err != nil {
+				return
+			}
+			var state ContractState
+			json.Unmarshal(m.state[CONTRACTSTATEKEY], &state)
+			if state.Version != MYVERSION || state.UpgradedFrom != tt.wantUpgradedFrom {
+				t.Fatalf("unexpected contract state %+v", state)
+			}
+			if tt.wantUpgradedFrom != "" {
+				if state.Status != STATUSPAUSED {
+					t.Fatal("the upgrade did not keep the status")
+				}
+				if m.lastEvent().Name != "contractUpgraded" {
+					t.Fatalf("event %s, expected contractUpgraded", m.lastEvent().Name)
+				}
+			}
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b    string
+		want    int
+		wantErr string
+	}{
+		{"1.0", "1.1", -1, ""},
+		{"1.1", "1.1", 0, ""},
+		{"1.10", "1.9", 1, ""},
+		{"1", "1.0.0", 0, ""},
+		{"2", "1.9", 1, ""},
+		{"1.x", "1.1", 0, "Invalid contract version: 1.x"},
+		{"1.1", "", 0, "Invalid contract version: "},
+	}
+	for _, tt := range tests {
+		got, err := compareVersions(tt.a, tt.b)
+		wantError(t, err, tt.wantErr)
+		if got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, expected %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
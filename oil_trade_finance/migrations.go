@@ -0,0 +1,225 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+// State migrations
+//
+// Every asset state carries the schemaVersion of the contract that wrote it,
+// states written before schema versions were introduced are at 1.0. When the
+// layout of the asset state changes, a migration converting documents from
+// the previous contract version is added to assetMigrations. Old documents
+// are migrated lazily whenever they are read and written back at their next
+// update. migrateAll, reserved to admins, migrates them eagerly a batch of
+// keys at a time, each call returning the key to continue from.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// FIRSTSCHEMAVERSION is the schema version of the asset states that carry none
+const FIRSTSCHEMAVERSION string = "1.0"
+
+// DEFAULTMIGRATIONBATCH is the number of keys migrateAll scans when no batch
+// size is given, MAXMIGRATIONBATCH the most it scans in one transaction
+const DEFAULTMIGRATIONBATCH int = 100
+const MAXMIGRATIONBATCH int = 1000
+
+// MIGRATIONENDKEY leaves the scan of migrateAll unbounded, to the last key
+const MIGRATIONENDKEY string = ""
+
+// stateMigration converts an asset state document of one contract version
+// to the layout of the next
+type stateMigration struct {
+	from  string
+	to    string
+	apply func(doc map[string]interface{}) error
+}
+
+// assetMigrations is the path of the asset state layout from the first
+// schema version to MYVERSION, in order
+var assetMigrations = []stateMigration{
+	{"1.0", "1.1", migrateAssetState10},
+}
+
+// MigrationRequest is the argument of migrateAll
+type MigrationRequest struct {
+	StartKey  string `json:"startKey,omitempty"`  // key to resume the scan from, the first key when empty
+	BatchSize int    `json:"batchSize,omitempty"` // number of keys to scan
+}
+
+// MigrationBatch reports the outcome of one call to migrateAll
+type MigrationBatch struct {
+	Scanned  int    `json:"scanned"`           // keys scanned
+	Migrated int    `json:"migrated"`          // asset states written back in the current layout
+	NextKey  string `json:"nextKey,omitempty"` // startKey of the next call, absent once the scan is complete
+}
+
+//******************** migrateAll ********************/
+
+func (t *SimpleChaincode) migrateAll(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	var request MigrationRequest
+	var batch MigrationBatch
+
+	if len(args) != 1 {
+		return nil, errors.New("Incorrect number of arguments. Expecting a JSON string with an optional startKey and batchSize")
+	}
+	err := json.Unmarshal([]byte(args[0]), &request)
+	if err != nil {
+		return nil, errors.New("Unable to unmarshal migration request JSON data")
+	}
+	if request.BatchSize == 0 {
+		request.BatchSize = DEFAULTMIGRATIONBATCH
+	}
+	if request.BatchSize < 0 || request.BatchSize > MAXMIGRATIONBATCH {
+		return nil, errors.New("The batch size must be between 1 and " + fmt.Sprint(MAXMIGRATIONBATCH))
+	}
+
+	iter, err := stub.GetStateByRange(request.StartKey, MIGRATIONENDKEY)
+	if err != nil {
+		return nil, errors.New("Range query failed: " + fmt.Sprint(err))
+	}
+	defer iter.Close()
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, errors.New("Range query iteration failed: " + fmt.Sprint(err))
+		}
+		key, value := kv.Key, kv.Value
+		if batch.Scanned == request.BatchSize {
+			batch.NextKey = key
+			break
+		}
+		batch.Scanned++
+		if !isAssetStateKey(key) || len(value) == 0 {
+			continue
+		}
+		migrated, changed, err := migrateAssetBytes(value)
+		if err != nil {
+			return nil, errors.New("Unable to migrate asset " + key + ": " + fmt.Sprint(err))
+		}
+		if !changed {
+			continue
+		}
+		// the version is left alone, the asset reads the same as before
+		err = stub.PutState(key, migrated)
+		if err != nil {
+			return nil, errors.New("PUT ledger state failed: " + fmt.Sprint(err))
+		}
+		batch.Migrated++
+	}
+	return json.Marshal(batch)
+}
+
+/*********************************  internal: migrations ****************************/
+
+// getAssetBytes reads an asset state from the ledger in the layout of this
+// contract version, nil when the asset does not exist
+func getAssetBytes(stub shim.ChaincodeStubInterface, assetID string) ([]byte, error) {
+	if !isAssetStateKey(assetID) {
+		// a record of the contract, never an asset
+		return nil, nil
+	}
+	assetBytes, err := stub.GetState(assetID)
+	if err != nil {
+		return nil, errors.New("Unable to get asset state from ledger: " + fmt.Sprint(err))
+	}
+	if len(assetBytes) == 0 {
+		return nil, nil
+	}
+	assetBytes, _, err = migrateAssetBytes(assetBytes)
+	if err != nil {
+		return nil, errors.New("Unable to migrate asset " + assetID + ": " + fmt.Sprint(err))
+	}
+	return assetBytes, nil
+}
+
+// migrateAssetBytes runs the migrations from the schema version of a stored
+// asset state to MYVERSION, returning true when the document changed
+func migrateAssetBytes(assetBytes []byte) ([]byte, bool, error) {
+	var doc map[string]interface{}
+
+	err := json.Unmarshal(assetBytes, &doc)
+	if err != nil {
+		return nil, false, errors.New("Unable to unmarshal state data obtained from ledger")
+	}
+	version := FIRSTSCHEMAVERSION
+	if v, found := doc["schemaVersion"].(string); found {
+		version = v
+	}
+	order, err := compareVersions(version, MYVERSION)
+	if err != nil {
+		return nil, false, err
+	}
+	if order > 0 {
+		return nil, false, errors.New("The state was written by contract version " + version + ", newer than " + MYVERSION)
+	}
+	if order == 0 {
+		return assetBytes, false, nil
+	}
+	for version != MYVERSION {
+		var step *stateMigration
+		for i := range assetMigrations {
+			if assetMigrations[i].from == version {
+				step = &assetMigrations[i]
+				break
+			}
+		}
+		if step == nil {
+			return nil, false, errors.New("No migration from schema version " + version + " to " + MYVERSION)
+		}
+		err = step.apply(doc)
+		if err != nil {
+			return nil, false, errors.New("Migration from schema version " + step.from + " to " + step.to + " failed: " + fmt.Sprint(err))
+		}
+		version = step.to
+	}
+	doc["schemaVersion"] = MYVERSION
+	assetBytes, err = json.Marshal(doc)
+	if err != nil {
+		return nil, false, errors.New("Marshal failed for migrated asset state" + fmt.Sprint(err))
+	}
+	return assetBytes, true, nil
+}
+
+// isAssetStateKey tells the keys of asset states from those of the contract
+// and trade states and of the records kept next to assets
+func isAssetStateKey(key string) bool {
+	if key == CONTRACTSTATEKEY || key == TRADESTATEKEY {
+		return false
+	}
+	for _, prefix := range assetRecordKeyPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// migrateAssetState10 converts a 1.0 asset state: the temperature of the
+// simple contract layout becomes maxTemperature, and alerts kept as a plain
+// string are dropped, the rules raise them again at the next update
+func migrateAssetState10(doc map[string]interface{}) error {
+	if temperature, found := doc["temperature"]; found {
+		if _, found := doc["maxTemperature"]; !found {
+			doc["maxTemperature"] = temperature
+		}
+		delete(doc, "temperature")
+	}
+	if _, isString := doc["alerts"].(string); isString {
+		delete(doc, "alerts")
+	}
+	return nil
+}
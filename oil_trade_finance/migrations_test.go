@@ -0,0 +1,147 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// legacyAssets are asset states as the 1.0 contract wrote them
+var legacyAssets = map[string]string{
+	"T1": `{"assetID":"T1","assetType":"CrudeTank","temperature":20,"alerts":"OVERTEMP"}`,
+	"T2": `{"assetID":"T2","assetType":"CrudeTank","maxTemperature":30}`,
+	"T3": `{"assetID":"T3","assetType":"CrudeTank","temperature":20,"maxTemperature":25}`,
+}
+
+func newLegacyContract(t *testing.T) *mockStub {
+	m := newTestContract(t)
+	for id, state := range legacyAssets {
+		m.state[id] = []byte(state)
+	}
+	return m
+}
+
+func TestMigrateAll(t *testing.T) {
+	steps := []struct {
+		request string
+		want    MigrationBatch
+	}{
+		// the contract state key sorts before the assets
+		{`{"batchSize":2}`, MigrationBatch{Scanned: 2, Migrated: 1, NextKey: "T2"}},
+		{`{"startKey":"T2","batchSize":2}`, MigrationBatch{Scanned: 2, Migrated: 2, NextKey: TRADESTATEKEY}},
+		{`{"startKey":"` + TRADESTATEKEY + `"}`, MigrationBatch{Scanned: 1}},
+		// a second pass has nothing left to do
+		{`{}`, MigrationBatch{Scanned: 5}},
+	}
+
+	m := newLegacyContract(t)
+	for i, step := range steps {
+		var batch MigrationBatch
+		json.Unmarshal(m.mustInvoke("migrateAll", step.request), &batch)
+		if batch != step.want {
+			t.Fatalf("step %d: batch %+v, expected %+v", i, batch, step.want)
+		}
+	}
+	for id := range legacyAssets {
+		var doc map[string]interface{}
+		json.Unmarshal(m.state[id], &doc)
+		if doc["schemaVersion"] != MYVERSION || doc["temperature"] != nil {
+			t.Errorf("%s was not migrated: %s", id, m.state[id])
+		}
+	}
+}
+
+func TestMigrateAllRefused(t *testing.T) {
+	tests := []struct {
+		name    string
+		request string
+		wantErr string
+	}{
+		{"batch too large", `{"batchSize":1001}`, "The batch size must be between 1 and 1000"},
+		{"negative batch", `{"batchSize":-1}`, "The batch size must be between 1 and 1000"},
+		{"newer state", `{}`, "Unable to migrate asset T4: The state was written by contract version 1.2, newer than 1.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newLegacyContract(t)
+			m.state["T4"] = []byte(`{"assetID":"T4","schemaVersion":"1.2"}`)
+			_, err := m.invoke("migrateAll", tt.request)
+			wantError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestLazyMigration(t *testing.T) {
+	m := newLegacyContract(t)
+	state := m.asset("T1")
+	if *state.MaxTemperature != 20 || *state.SchemaVersion != MYVERSION || state.Alerts != nil {
+		t.Fatalf("T1 was not migrated on read: %+v", state)
+	}
+	if string(m.state["T1"]) != legacyAssets["T1"] {
+		t.Fatal("reading T1 wrote it back")
+	}
+	// the next update writes the migrated state and runs the rules again
+	m.mustInvoke("updateAsset", `{"assetID":"T1","maxTemperature":21}`)
+	state = m.asset("T1")
+	if *state.SchemaVersion != MYVERSION || *state.MaxTemperature != 21 || *state.Version != 1 {
+		t.Fatalf("unexpected state after the update %+v", state)
+	}
+}
+
+func TestMigrateAssetState10(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want string
+	}{
+		{"temperature renamed", `{"temperature":20}`, `{"maxTemperature":20}`},
+		{"maxTemperature kept", `{"temperature":20,"maxTemperature":25}`, `{"maxTemperature":25}`},
+		{"string alerts dropped", `{"alerts":"OVERTEMP"}`, `{}`},
+		{"structured alerts kept", `{"alerts":{"active":["OVERTEMP"]}}`, `{"alerts":{"active":["OVERTEMP"]}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc, want map[string]interface{}
+			json.Unmarshal([]byte(tt.doc), &doc)
+			json.Unmarshal([]byte(tt.want), &want)
+			if err := migrateAssetState10(doc); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(doc, want) {
+				t.Fatalf("migrated %s into %v, expected %s", tt.doc, doc, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsAssetStateKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"T1", true},
+		{CONTRACTSTATEKEY, false},
+		{TRADESTATEKEY, false},
+	}
+	for _, prefix := range assetRecordKeyPrefixes {
+		tests = append(tests, struct {
+			key  string
+			want bool
+		}{prefix + "T1", false})
+	}
+	for _, tt := range tests {
+		if got := isAssetStateKey(tt.key); got != tt.want {
+			t.Errorf("isAssetStateKey(%q) = %v, expected %v", tt.key, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,417 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+// In-memory ledger for the tests
+//
+// mockStub stands in for the peer. It keeps the world state in a map and runs
+// every call as a transaction of its own, with a sequential ID and a clock
+// that moves one hour per transaction, so that every run of a test sees the
+// same IDs and timestamps. Like the peer, reads see the state committed by
+// earlier transactions only, the writes of a transaction are committed when
+// it succeeds and dropped when it fails, and a transaction publishes at most
+// one event, the last one set. The caller identity is a certificate with the
+// common name and role attribute that the test chooses.
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// mockStartTime is the timestamp of the first transaction
+var mockStartTime = time.Date(2017, time.March, 20, 8, 0, 0, 0, time.UTC)
+
+// mockTxInterval is the time between two transactions
+const mockTxInterval = time.Hour
+
+// mockMSPID is the MSP of every caller
+const mockMSPID string = "Org1MSP"
+
+// attributeOID is the certificate extension in which the CA puts attributes
+var attributeOID = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 7, 8, 1}
+
+// mockEvent is the event published by a transaction
+type mockEvent struct {
+	TxID    string
+	Name    string
+	Payload []byte
+}
+
+// mockStub is an in-memory shim.ChaincodeStubInterface. The functions the
+// contract does not use are left to the nil interface it embeds and panic.
+type mockStub struct {
+	shim.ChaincodeStubInterface
+
+	t       *testing.T
+	cc      *SimpleChaincode
+	state   map[string][]byte // committed world state
+	writes  map[string][]byte // writes of the running transaction, nil deletes
+	args    []string          // function and arguments of the running transaction
+	txCount int
+	txID    string
+	txTime  time.Time
+	creator []byte
+	event   *mockEvent  // event set by the running transaction
+	events  []mockEvent // events of the committed transactions
+}
+
+// newMockStub returns an empty ledger on which admin calls the contract
+func newMockStub(t *testing.T) *mockStub {
+	m := &mockStub{
+		t:      t,
+		cc:     new(SimpleChaincode),
+		state:  make(map[string][]byte),
+		txTime: mockStartTime.Add(-mockTxInterval),
+	}
+	return m.as("admin", ADMINROLE)
+}
+
+// newTestContract returns a ledger on which the contract is initialized
+func newTestContract(t *testing.T) *mockStub {
+	m := newMockStub(t)
+	m.mustInit(`{"version":"`+MYVERSION+`"}`, `{"tradeID":"`+TRADEID+`"}`)
+	return m
+}
+
+// as makes the following calls under the identity of name, with roles a
+// comma separated list as in the role attribute, none when empty
+func (m *mockStub) as(name string, roles string) *mockStub {
+	creator, err := mockCreator(name, roles)
+	if err != nil {
+		m.t.Fatalf("unable to create the identity of %s: %v", name, err)
+	}
+	m.creator = creator
+	return m
+}
+
+/*********************************  transactions ****************************/
+
+// init runs Init with the arguments given
+func (m *mockStub) init(args ...string) error {
+	_, err := m.transact(true, false, "init", args)
+	return err
+}
+
+// invoke runs an invoke and returns its payload
+func (m *mockStub) invoke(function string, args ...string) ([]byte, error) {
+	return m.transact(false, false, function, args)
+}
+
+// query runs a query, which fails the test if it writes to the ledger
+func (m *mockStub) query(function string, args ...string) ([]byte, error) {
+	return m.transact(false, true, function, args)
+}
+
+func (m *mockStub) mustInit(args ...string) {
+	m.t.Helper()
+	if err := m.init(args...); err != nil {
+		m.t.Fatalf("init %v: %v", args, err)
+	}
+}
+
+func (m *mockStub) mustInvoke(function string, args ...string) []byte {
+	m.t.Helper()
+	payload, err := m.invoke(function, args...)
+	if err != nil {
+		m.t.Fatalf("%s %v: %v", function, args, err)
+	}
+	return payload
+}
+
+func (m *mockStub) mustQuery(function string, args ...string) []byte {
+	m.t.Helper()
+	payload, err := m.query(function, args...)
+	if err != nil {
+		m.t.Fatalf("%s %v: %v", function, args, err)
+	}
+	return payload
+}
+
+// asset reads the state of an asset through readAsset
+func (m *mockStub) asset(assetID string) AssetState {
+	var state AssetState
+
+	m.t.Helper()
+	err := json.Unmarshal(m.mustQuery("readAsset", `{"assetID":"`+assetID+`"}`), &state)
+	if err != nil {
+		m.t.Fatalf("readAsset %s: %v", assetID, err)
+	}
+	return state
+}
+
+// lastEvent returns the event of the last transaction that published one
+func (m *mockStub) lastEvent() mockEvent {
+	m.t.Helper()
+	if len(m.events) == 0 {
+		m.t.Fatal("no event was published")
+	}
+	return m.events[len(m.events)-1]
+}
+
+// transact runs one transaction and commits its writes and event when it
+// succeeds, readOnly fails the test if a query writes
+func (m *mockStub) transact(init bool, readOnly bool, function string, args []string) ([]byte, error) {
+	m.txCount++
+	m.txID = fmt.Sprintf("tx%04d", m.txCount)
+	m.txTime = m.txTime.Add(mockTxInterval)
+	m.args = append([]string{function}, args...)
+	m.writes = make(map[string][]byte)
+	m.event = nil
+
+	var resp pb.Response
+	if init {
+		resp = m.cc.Init(m)
+	} else {
+		resp = m.cc.Invoke(m)
+	}
+	if resp.Status != shim.OK {
+		return nil, errors.New(resp.Message)
+	}
+	if readOnly && len(m.writes) > 0 {
+		m.t.Errorf("query %s wrote to the ledger: %v", function, m.writeKeys())
+	}
+	for key, value := range m.writes {
+		if value == nil {
+			delete(m.state, key)
+			continue
+		}
+		m.state[key] = value
+	}
+	if m.event != nil {
+		m.events = append(m.events, *m.event)
+	}
+	return resp.Payload, nil
+}
+
+func (m *mockStub) writeKeys() []string {
+	keys := make([]string, 0, len(m.writes))
+	for key := range m.writes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+/*********************************  shim.ChaincodeStubInterface ****************************/
+
+func (m *mockStub) GetArgs() [][]byte {
+	args := make([][]byte, len(m.args))
+	for i, arg := range m.args {
+		args[i] = []byte(arg)
+	}
+	return args
+}
+
+func (m *mockStub) GetStringArgs() []string {
+	return m.args
+}
+
+func (m *mockStub) GetFunctionAndParameters() (string, []string) {
+	if len(m.args) == 0 {
+		return "", []string{}
+	}
+	return m.args[0], m.args[1:]
+}
+
+func (m *mockStub) GetTxID() string {
+	return m.txID
+}
+
+func (m *mockStub) GetChannelID() string {
+	return "mychannel"
+}
+
+func (m *mockStub) GetTxTimestamp() (*timestamppb.Timestamp, error) {
+	return timestamppb.New(m.txTime), nil
+}
+
+func (m *mockStub) GetCreator() ([]byte, error) {
+	return m.creator, nil
+}
+
+func (m *mockStub) GetState(key string) ([]byte, error) {
+	return m.state[key], nil
+}
+
+func (m *mockStub) PutState(key string, value []byte) error {
+	if key == "" {
+		return errors.New("key must not be an empty string")
+	}
+	if len(value) == 0 {
+		// the peer treats an empty value as a delete
+		value = nil
+	}
+	m.writes[key] = value
+	return nil
+}
+
+func (m *mockStub) DelState(key string) error {
+	if key == "" {
+		return errors.New("key must not be an empty string")
+	}
+	m.writes[key] = nil
+	return nil
+}
+
+// GetStateByRange returns the committed keys in [startKey, endKey) in key
+// order, an empty endKey leaves the range open
+func (m *mockStub) GetStateByRange(startKey string, endKey string) (shim.StateQueryIteratorInterface, error) {
+	iter := &mockIterator{}
+	for key := range m.state {
+		if key >= startKey && (endKey == "" || key < endKey) {
+			iter.keys = append(iter.keys, key)
+		}
+	}
+	sort.Strings(iter.keys)
+	for _, key := range iter.keys {
+		iter.values = append(iter.values, m.state[key])
+	}
+	return iter, nil
+}
+
+func (m *mockStub) SetEvent(name string, payload []byte) error {
+	if name == "" {
+		return errors.New("event name can not be empty string")
+	}
+	m.event = &mockEvent{TxID: m.txID, Name: name, Payload: payload}
+	return nil
+}
+
+// mockIterator iterates over a snapshot of the keys of a range
+type mockIterator struct {
+	keys   []string
+	values [][]byte
+	next   int
+}
+
+func (it *mockIterator) HasNext() bool {
+	return it.next < len(it.keys)
+}
+
+func (it *mockIterator) Next() (*queryresult.KV, error) {
+	if !it.HasNext() {
+		return nil, errors.New("the iterator is exhausted")
+	}
+	it.next++
+	return &queryresult.KV{Key: it.keys[it.next-1], Value: it.values[it.next-1]}, nil
+}
+
+func (it *mockIterator) Close() error {
+	return nil
+}
+
+/*********************************  identities ****************************/
+
+// mockKey signs the certificates of every caller
+var mockKey *ecdsa.PrivateKey
+
+// mockCreators caches the serialized identities by name and roles
+var mockCreators = make(map[string][]byte)
+
+// mockCreator returns the serialized identity of a caller, with a self
+// signed certificate in which the roles are a CA attribute
+func mockCreator(name string, roles string) ([]byte, error) {
+	var err error
+
+	if creator, found := mockCreators[name+"|"+roles]; found {
+		return creator, nil
+	}
+	if mockKey == nil {
+		mockKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(int64(len(mockCreators) + 1)),
+		Subject:      pkix.Name{CommonName: name, Organization: []string{mockMSPID}},
+		NotBefore:    mockStartTime.AddDate(-1, 0, 0),
+		NotAfter:     mockStartTime.AddDate(10, 0, 0),
+	}
+	if strings.TrimSpace(roles) != "" {
+		attrs, err := json.Marshal(map[string]interface{}{"attrs": map[string]string{ROLEATTRIBUTE: roles}})
+		if err != nil {
+			return nil, err
+		}
+		template.ExtraExtensions = []pkix.Extension{{Id: attributeOID, Value: attrs}}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &mockKey.PublicKey, mockKey)
+	if err != nil {
+		return nil, err
+	}
+	creator, err := proto.Marshal(&msp.SerializedIdentity{
+		Mspid:   mockMSPID,
+		IdBytes: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	})
+	if err != nil {
+		return nil, err
+	}
+	mockCreators[name+"|"+roles] = creator
+	return creator, nil
+}
+
+/*********************************  assertions ****************************/
+
+// wantError fails the test unless err is an error containing want, or nil
+// when want is empty
+func wantError(t *testing.T, err error, want string) {
+	t.Helper()
+	if want == "" {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return
+	}
+	if err == nil {
+		t.Fatalf("expected an error containing %q, got none", want)
+	}
+	if !strings.Contains(err.Error(), want) {
+		t.Fatalf("expected an error containing %q, got %q", want, err.Error())
+	}
+}
+
+// alertNames returns the alert names sorted, nil for none
+func alertNames(names AlertNameArray) []string {
+	if len(names) == 0 {
+		return nil
+	}
+	sorted := append([]string(nil), names...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// activeAlerts returns the sorted active alerts of a state
+func activeAlerts(state AssetState) []string {
+	if state.Alerts == nil {
+		return nil
+	}
+	return alertNames(state.Alerts.Active)
+}
@@ -27,12 +27,19 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"reflect"
+	golog "log"
+	"math"
+	"os"
 	"strings"
+	"time"
 
-	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
 )
 
+var log = newLogger("oil_trade_finance")
+
 // SimpleChaincode example simple Chaincode implementation
 type SimpleChaincode struct {
 }
@@ -41,8 +48,8 @@ type SimpleChaincode struct {
 const CONTRACTSTATEKEY string = "ContractStateKey"
 
 // MYVERSION and DEFAULTSTATUS must be used to deploy the contract
-const MYVERSION string = "1.0"
-const DEFAULTSTATUS uint8 = 0
+const MYVERSION string = "1.1"
+const DEFAULTSTATUS uint8 = STATUSACTIVE
 
 // TRADESTATEKEY is used to store trade state into world state
 const TRADESTATEKEY string = "TradeStateKey"
@@ -54,10 +61,14 @@ const TRADEID string = "0476219"
 // asset and contract state
 // ************************************
 
-// ContractState holds the contract version
+// ContractState holds the contract version and status
 type ContractState struct {
-	Version string `json:"version"`
-	Status  uint8  `json:"status"`
+	Version            string `json:"version"`
+	Status             uint8  `json:"status"`
+	StatusReason       string `json:"statusReason,omitempty"`       // why the status was last changed
+	StatusChangedBy    string `json:"statusChangedBy,omitempty"`    // admin that last changed the status
+	StatusTxnTimestamp string `json:"statusTxntimestamp,omitempty"` // transaction timestamp of the last change
+	UpgradedFrom       string `json:"upgradedFrom,omitempty"`       // version the contract was last upgraded from
 }
 
 // Geolocation stores lat and long
@@ -68,16 +79,36 @@ type Geolocation struct {
 
 // AssetState stores current state for any assset
 type AssetState struct {
-	AssetID        *string      `json:"assetID,omitempty"`        // all assets must have an ID, primary key of contract
-	Location       *Geolocation `json:"location,omitempty"`       // current asset location
-	MaxTemperature *float64     `json:"maxTemperature,omitempty"` // asset temp
-	MaxHumidity    *float64     `json:"maxHumidity,omitempty"`    // asset humidity
-	Carrier        *string      `json:"carrier,omitempty"`        // the name of the carrier
+	AssetID              *string      `json:"assetID,omitempty"`              // all assets must have an ID, primary key of contract
+	AssetType            *string      `json:"assetType,omitempty"`            // name of the type in the asset type registry
+	Location             *Geolocation `json:"location,omitempty"`             // current asset location
+	MaxTemperature       *float64     `json:"maxTemperature,omitempty"`       // asset temp
+	MaxHumidity          *float64     `json:"maxHumidity,omitempty"`          // asset humidity
+	Carrier              *string      `json:"carrier,omitempty"`              // the name of the carrier
+	SealBroken           *bool        `json:"sealBroken,omitempty"`           // the seal was found broken
+	Timestamp            *string      `json:"timestamp,omitempty"`            // device timestamp of the last event
+	TxnTimestamp         *string      `json:"txntimestamp,omitempty"`         // transaction timestamp of the last event
+	TxnID                *string      `json:"txnuuid,omitempty"`              // transaction UUID of the last event
+	Alerts               *AlertStatus `json:"alerts,omitempty"`               // calculated by the rules
+	Compliance           *bool        `json:"compliant,omitempty"`            // calculated by the rules
+	Parent               *string      `json:"parent,omitempty"`               // the asset containing this one
+	NoncompliantChildren []string     `json:"noncompliantChildren,omitempty"` // contained assets that are not compliant
+	Extension            ArgsMap      `json:"extension,omitempty"`            // application-managed state, opaque to the contract
+	Version              *int64       `json:"version,omitempty"`              // incremented by every write of the state
+	SchemaVersion        *string      `json:"schemaVersion,omitempty"`        // contract version of the layout of the state
+	ReadingTimes         ReadingTimes `json:"readingTimes,omitempty"`         // when the readings checked for their rate were taken
 	//Event          *Event       `json:"event,omitempty"`
-	Alerts         *string      `json:"alerts,omitempty`
-	Compliance     *bool.       `json:"compliant,omitempty"`
 }
 
+// ReadingTime records when a reading was taken
+type ReadingTime struct {
+	Timestamp    *string `json:"timestamp,omitempty"`    // device timestamp of the event that carried it
+	TxnTimestamp *string `json:"txntimestamp,omitempty"` // transaction timestamp of the event that carried it
+}
+
+// ReadingTimes maps the name of a reading to when it was last taken
+type ReadingTimes map[string]ReadingTime
+
 type Event struct {
 	Name *string `json:"name,omitempty"` // name of the Watson IoT event received
 	Date *string `json:"date,omitempty"` // date of reception
@@ -91,12 +122,23 @@ type TradeState struct {
 // deploy callback mode
 // ************************************
 
-// Init is called during deploy
-func (t *SimpleChaincode) Init(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
+// Init is called when the contract is instantiated or upgraded
+func (t *SimpleChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	_, args := stub.GetFunctionAndParameters()
+	return response(t.initContract(stub, args))
+}
+
+// initContract checks the version and trade arguments of Init and sets up
+// the contract and trade states
+func (t *SimpleChaincode) initContract(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
 	var contractStateArg ContractState
 	var tradeStateArg TradeState
 	var err error
 
+	err = checkAccess(stub, "init")
+	if err != nil {
+		return nil, err
+	}
 	if len(args) != 2 {
 		return nil, errors.New("init expects 2 arguments, a JSON string with tagged version string and the id of the trade")
 	}
@@ -109,17 +151,6 @@ func (t *SimpleChaincode) Init(stub shim.ChaincodeStubInterface, function string
 	if contractStateArg.Version != MYVERSION {
 		return nil, errors.New("Contract version " + MYVERSION + " must match version argument: " + contractStateArg.Version)
 	}
-	// set status to default (0)
-	contractStateArg.Status = DEFAULTSTATUS
-
-	contractStateJSON, err := json.Marshal(contractStateArg)
-	if err != nil {
-		return nil, errors.New("Marshal failed for contract state" + fmt.Sprint(err))
-	}
-	err = stub.PutState(CONTRACTSTATEKEY, contractStateJSON)
-	if err != nil {
-		return nil, errors.New("Contract state failed PUT to ledger: " + fmt.Sprint(err))
-	}
 
 	// handle trade state
 	err = json.Unmarshal([]byte(args[1]), &tradeStateArg)
@@ -129,13 +160,15 @@ func (t *SimpleChaincode) Init(stub shim.ChaincodeStubInterface, function string
 	if tradeStateArg.TradeID != TRADEID {
 		return nil, errors.New("Trade id " + TRADEID + " must match trade id: " + tradeStateArg.TradeID)
 	}
-	tradeStateJSON, err := json.Marshal(tradeStateArg)
+
+	// Init runs again on upgrade, what the ledger holds is kept
+	err = t.initContractState(stub)
 	if err != nil {
-		return nil, errors.New("Marshal failed for trade state" + fmt.Sprint(err))
+		return nil, err
 	}
-	err = stub.PutState(TRADESTATEKEY, tradeStateJSON)
+	err = initTradeState(stub, tradeStateArg)
 	if err != nil {
-		return nil, errors.New("Trade state failed PUT to ledger: " + fmt.Sprint(err))
+		return nil, err
 	}
 
 	return nil, nil
@@ -145,8 +178,51 @@ func (t *SimpleChaincode) Init(stub shim.ChaincodeStubInterface, function string
 // deploy and invoke callback mode
 // ************************************
 
-// Invoke is called when an invoke message is received
-func (t *SimpleChaincode) Invoke(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
+// queryFunctions only read the ledger, they are served whatever the status
+// of the contract but paused
+var queryFunctions = map[string]bool{
+	"readAsset":                  true,
+	"readAssetDevices":           true,
+	"readAssetTree":              true,
+	"readAssetTypes":             true,
+	"readAssetSamples":           true,
+	"readAssetSchemas":           true,
+	"readContractState":          true,
+	"readTradeState":             true,
+	"readCustodyChain":           true,
+	"readDeletedAssets":          true,
+	"readAlertSuppressions":      true,
+	"readLatchedAlertClearances": true,
+	"previewUpdate":              true,
+}
+
+// Invoke is called for every transaction, the first argument names the
+// function. Queries come through Invoke too and are told apart by name.
+func (t *SimpleChaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	function, args := stub.GetFunctionAndParameters()
+	if queryFunctions[function] {
+		return response(t.query(stub, function, args))
+	}
+	return response(t.invoke(stub, function, args))
+}
+
+// invoke runs a function that writes to the ledger
+func (t *SimpleChaincode) invoke(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
+	// refuse callers without a role allowed by the policy
+	err := checkAccess(stub, function)
+	if err != nil {
+		return nil, err
+	}
+	// refuse writes unless the contract is active
+	err = checkContractStatus(stub, function, true)
+	if err != nil {
+		return nil, err
+	}
+	// refuse arguments that do not match the published schema
+	err = validateArgs(function, args)
+	if err != nil {
+		return nil, err
+	}
 	// Handle different functions
 	if function == "createAsset" {
 		// create assetID
@@ -154,19 +230,82 @@ func (t *SimpleChaincode) Invoke(stub shim.ChaincodeStubInterface, function stri
 	} else if function == "updateAsset" {
 		// create assetID
 		return t.updateAsset(stub, args)
+	} else if function == "patchAsset" {
+		// applies a JSON patch to the stored state of an asset
+		return t.patchAsset(stub, args)
+	} else if function == "updateAssets" {
+		// applies a batch of events in device timestamp order
+		return t.updateAssets(stub, args)
 	} else if function == "deleteAsset" {
 		// Deletes an asset by ID from the ledger
 		return t.deleteAsset(stub, args)
+	} else if function == "suppressAlerts" {
+		// suppresses alerts on an asset during a maintenance window
+		return t.suppressAlerts(stub, args)
+	} else if function == "restoreAsset" {
+		// removes the tombstone of a deleted asset
+		return t.restoreAsset(stub, args)
+	} else if function == "purgeAsset" {
+		// admin removes an asset and its records from the ledger for good
+		return t.purgeAsset(stub, args)
+	} else if function == "proposeHandoff" {
+		// current carrier offers the asset to the next carrier
+		return t.proposeHandoff(stub, args)
+	} else if function == "acceptHandoff" {
+		// next carrier takes custody of the asset
+		return t.acceptHandoff(stub, args)
+	} else if function == "attachAsset" {
+		// places an asset inside a containing asset
+		return t.attachAsset(stub, args)
+	} else if function == "detachAsset" {
+		// takes an asset out of its containing asset
+		return t.detachAsset(stub, args)
+	} else if function == "clearLatchedAlert" {
+		// inspector clears an alert that the rules cannot clear
+		return t.clearLatchedAlert(stub, args)
+	} else if function == "registerDevice" {
+		// allows a device to send the events of an asset
+		return t.registerDevice(stub, args)
+	} else if function == "deregisterDevice" {
+		// withdraws a device from an asset
+		return t.deregisterDevice(stub, args)
+	} else if function == "pauseContract" {
+		// admin freezes the contract, or makes it read-only
+		return t.pauseContract(stub, args)
+	} else if function == "resumeContract" {
+		// admin makes the contract active again
+		return t.resumeContract(stub, args)
+	} else if function == "retireContract" {
+		// admin makes the contract read-only for good
+		return t.retireContract(stub, args)
+	} else if function == "migrateAll" {
+		// admin migrates a batch of asset states to the current layout
+		return t.migrateAll(stub, args)
 	}
 	return nil, errors.New("Received unknown invocation: " + function)
 }
 
 // ************************************
-// query callback mode
+// query mode
 // ************************************
 
-// Query is called when a query message is received
-func (t *SimpleChaincode) Query(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
+// query runs a function that reads the ledger
+func (t *SimpleChaincode) query(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
+	// refuse callers without a role allowed by the policy
+	err := checkAccess(stub, function)
+	if err != nil {
+		return nil, err
+	}
+	// a paused contract serves nothing but its own state
+	err = checkContractStatus(stub, function, false)
+	if err != nil {
+		return nil, err
+	}
+	// refuse arguments that do not match the published schema
+	err = validateArgs(function, args)
+	if err != nil {
+		return nil, err
+	}
 	// Handle different functions
 	if function == "readAsset" {
 		// gets the state for an assetID as a JSON struct
@@ -182,18 +321,41 @@ func (t *SimpleChaincode) Query(stub shim.ChaincodeStubInterface, function strin
 		return t.readAssetSchemas(stub, args)
 	} else if function == "readContractState" {
 		return t.readContractState(stub, args)
+	} else if function == "readDeletedAssets" {
+		// returns the tombstones of all deleted assets
+		return t.readDeletedAssets(stub, args)
+	} else if function == "readAssetTypes" {
+		// returns the asset type registry
+		return t.readAssetTypes(stub, args)
+	} else if function == "readAssetDevices" {
+		// returns the devices registered to an asset
+		return t.readAssetDevices(stub, args)
+	} else if function == "readAssetTree" {
+		// returns an asset with everything it contains
+		return t.readAssetTree(stub, args)
+	} else if function == "readCustodyChain" {
+		// returns the custody transfers of an asset
+		return t.readCustodyChain(stub, args)
+	} else if function == "previewUpdate" {
+		// dry run of updateAsset, nothing is written
+		return t.previewUpdate(stub, args)
+	} else if function == "readAlertSuppressions" {
+		// returns the suppression audit trail of an asset
+		return t.readAlertSuppressions(stub, args)
+	} else if function == "readLatchedAlertClearances" {
+		// returns the latched alert clearances of an asset
+		return t.readLatchedAlertClearances(stub, args)
 	}
 
 	return nil, errors.New("Received unknown invocation: " + function)
 }
 
-/**********main implementation *************/
-
-func main() {
-	err := shim.Start(new(SimpleChaincode))
+// response turns the outcome of a function into a peer response
+func response(payload []byte, err error) pb.Response {
 	if err != nil {
-		fmt.Printf("Error starting Simple Chaincode: %s", err)
+		return shim.Error(err.Error())
 	}
+	return shim.Success(payload)
 }
 
 /*****************ASSET CRUD INTERFACE starts here************/
@@ -220,6 +382,7 @@ func (t *SimpleChaincode) deleteAsset(stub shim.ChaincodeStubInterface, args []s
 	var assetID string // asset ID
 	var err error
 	var stateIn AssetState
+	var tombstone Tombstone
 
 	// validate input data for number of args, Unmarshaling to asset state and obtain asset id
 	stateIn, err = t.validateInput(args)
@@ -227,10 +390,44 @@ func (t *SimpleChaincode) deleteAsset(stub shim.ChaincodeStubInterface, args []s
 		return nil, err
 	}
 	assetID = *stateIn.AssetID
-	// Delete the key / asset from the ledger
-	err = stub.DelState(assetID)
+	err = json.Unmarshal([]byte(args[0]), &tombstone)
+	if err != nil {
+		return nil, errors.New("Unable to unmarshal input JSON data")
+	}
+	tombstone.AssetID = assetID
+
+	assetBytes, err := getAssetBytes(stub, assetID)
+	if err != nil || len(assetBytes) == 0 {
+		return nil, errors.New("Asset does not exist!")
+	}
+	var state AssetState
+	err = json.Unmarshal(assetBytes, &state)
+	if err != nil {
+		return nil, errors.New("Unable to unmarshal state data obtained from ledger")
+	}
+	// only those in charge of the asset may delete it
+	err = t.checkOwnership(stub, assetID, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = checkExpectedVersion(args[0], assetID, assetVersion(state))
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := t.isDeleted(stub, assetID)
+	if err != nil {
+		return nil, err
+	}
+	if deleted {
+		return nil, errors.New("Asset " + assetID + " is already deleted")
+	}
+	err = t.checkDetached(stub, assetID)
+	if err != nil {
+		return nil, err
+	}
+	// The asset stays on the ledger, the tombstone hides it
+	err = t.putTombstone(stub, tombstone)
 	if err != nil {
-		err = errors.New("DELSTATE failed! : " + fmt.Sprint(err))
 		return nil, err
 	}
 	return nil, nil
@@ -244,6 +441,9 @@ func (t *SimpleChaincode) readAsset(stub shim.ChaincodeStubInterface, args []str
 	var assetID string // asset ID
 	var err error
 	var state AssetState
+	var options struct {
+		IncludeDeleted bool `json:"includeDeleted"` // also return deleted assets
+	}
 
 	// validate input data for number of args, Unmarshaling to asset state and obtain asset id
 	stateIn, err := t.validateInput(args)
@@ -251,9 +451,26 @@ func (t *SimpleChaincode) readAsset(stub shim.ChaincodeStubInterface, args []str
 		return nil, errors.New("Asset does not exist!")
 	}
 	assetID = *stateIn.AssetID
+	err = json.Unmarshal([]byte(args[0]), &options)
+	if err != nil {
+		return nil, errors.New("Unable to unmarshal input JSON data")
+	}
+	// deleted assets are hidden unless asked for
+	if !options.IncludeDeleted {
+		deleted, err := t.isDeleted(stub, assetID)
+		if err != nil {
+			return nil, err
+		}
+		if deleted {
+			return nil, errors.New("Asset does not exist!")
+		}
+	}
 	// Get the state from the ledger
-	assetBytes, err := stub.GetState(assetID)
-	if err != nil || len(assetBytes) == 0 {
+	assetBytes, err := getAssetBytes(stub, assetID)
+	if err != nil {
+		return nil, err
+	}
+	if len(assetBytes) == 0 {
 		err = errors.New("Unable to get asset state from ledger")
 		return nil, err
 	}
@@ -265,6 +482,43 @@ func (t *SimpleChaincode) readAsset(stub shim.ChaincodeStubInterface, args []str
 	return assetBytes, nil
 }
 
+//********************previewUpdate********************/
+
+// UpdatePreview is what an updateAsset event would do to an asset
+type UpdatePreview struct {
+	State   AssetState     `json:"state"`   // the state that would be written
+	Created bool           `json:"created"` // the event would create the asset
+	Raised  AlertNameArray `json:"raised"`  // alerts the event would raise
+	Cleared AlertNameArray `json:"cleared"` // alerts the event would clear
+}
+
+func (t *SimpleChaincode) previewUpdate(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	var preview UpdatePreview
+	var err error
+
+	// same path as createOrUpdateAsset, minus the write
+	preview.State, preview.Created, err = t.nextAssetState(stub, args)
+	if err != nil {
+		return nil, err
+	}
+	// the version that writing the state would give it
+	version := assetVersion(preview.State) + 1
+	preview.State.Version = &version
+	schemaVersion := MYVERSION
+	preview.State.SchemaVersion = &schemaVersion
+	preview.Raised = AlertNameArray{}
+	preview.Cleared = AlertNameArray{}
+	if preview.State.Alerts != nil {
+		preview.Raised = preview.State.Alerts.Raised
+		preview.Cleared = preview.State.Alerts.Cleared
+	}
+	previewJSON, err := json.Marshal(preview)
+	if err != nil {
+		return nil, errors.New("Marshal failed for update preview" + fmt.Sprint(err))
+	}
+	return previewJSON, nil
+}
+
 //********************readTradeState********************/
 
 func (t *SimpleChaincode) readTradeState(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
@@ -383,6 +637,12 @@ func (t *SimpleChaincode) validateInput(args []string) (stateIn AssetState, err
 			err = errors.New("AssetID not passed")
 			return state, err
 		}
+		// asset states share the keyspace with the contract and trade states
+		// and the records kept next to assets, an asset may not take their keys
+		if !isAssetStateKey(assetID) {
+			err = errors.New("Asset id " + assetID + " is reserved for the records of the contract")
+			return state, err
+		}
 	} else {
 		err = errors.New("Asset id is mandatory in the input JSON data")
 		return state, err
@@ -398,341 +658,883 @@ func (t *SimpleChaincode) createOrUpdateAsset(stub shim.ChaincodeStubInterface,
 	var assetID string // asset ID                    // used when looking in map
 	var err error
 	var stateIn AssetState
+
+	// contained and containing assets change in the same transaction, the
+	// overlay lets each step read what the previous ones wrote
+	overlay := newOverlayStub(stub)
+
+	stateIn, err = t.validateInput(args)
+	if err != nil {
+		return nil, err
+	}
+	assetID = *stateIn.AssetID
+	patch, err := eventPatch(args[0])
+	if err != nil {
+		return nil, err
+	}
+	// updateAsset and updateAssets create the assets they do not find, which
+	// only the roles that may call createAsset may do
+	assetBytes, err := getAssetBytes(overlay, assetID)
+	if err != nil {
+		return nil, err
+	}
+	if len(assetBytes) == 0 {
+		err = checkAccess(overlay, "createAsset")
+		if err != nil {
+			return nil, err
+		}
+	}
+	// only those in charge of the asset may send its events
+	err = t.checkOwnership(overlay, assetID, patch)
+	if err != nil {
+		return nil, err
+	}
+	if len(assetBytes) == 0 {
+		args, err = creatorCustody(overlay, args, patch)
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = t.writeAssetEvent(overlay, args, patch)
+	if err != nil {
+		return nil, err
+	}
+	return nil, overlay.commit()
+}
+
+/*********************************  internal: writeAssetEvent ****************************/
+
+// writeAssetEvent writes the state that an event produces along with the
+// assets it contains and those containing it, every change of an asset state
+// goes this way
+func (t *SimpleChaincode) writeAssetEvent(stub shim.ChaincodeStubInterface, args []string, patch map[string]interface{}) (AssetState, error) {
+	var stateStub AssetState
+	var err error
+
+	// contained assets move to the merged location of their container, they
+	// go first so that the container rolls up their fresh compliance
+	if _, found := patch["location"]; found {
+		moved, _, err := t.nextAssetState(stub, args)
+		if err != nil {
+			return stateStub, err
+		}
+		err = t.propagateLocation(stub, *moved.AssetID, moved.Location)
+		if err != nil {
+			return stateStub, err
+		}
+	}
+
+	// validate, merge and run the rules to obtain the new state
+	stateStub, _, err = t.nextAssetState(stub, args)
+	if err != nil {
+		return stateStub, err
+	}
+
+	// Write the new state to the ledger
+	err = t.putAssetState(stub, stateStub)
+	if err != nil {
+		return stateStub, err
+	}
+	// containing assets roll up the compliance of this one
+	err = t.rollUpAncestors(stub, stateStub.Parent)
+	if err != nil {
+		return stateStub, err
+	}
+	return stateStub, nil
+}
+
+/*********************************  internal: nextAssetState ****************************/
+
+// nextAssetState computes the state that an event produces from the stored
+// state without writing anything to the ledger. It also returns whether the
+// event creates the asset.
+func (t *SimpleChaincode) nextAssetState(stub shim.ChaincodeStubInterface, args []string) (AssetState, bool, error) {
+	var assetID string // asset ID                    // used when looking in map
+	var err error
+	var stateIn AssetState
 	var stateStub AssetState
+	var prior *ArgsMap // state before this event, nil on create
+	var created bool
 
 	// validate input data for number of args, Unmarshaling to asset state and obtain asset id
 
 	stateIn, err = t.validateInput(args)
 	if err != nil {
-		return nil, err
+		return stateStub, false, err
 	}
 	assetID = *stateIn.AssetID
-	// Partial updates introduced here
-	// Check if asset record existed in stub
-	assetBytes, err := stub.GetState(assetID)
-	if err != nil || len(assetBytes) == 0 {
+	// calculated properties are owned by the contract, never by the event
+	stateIn.TxnTimestamp = nil
+	stateIn.TxnID = nil
+	stateIn.Alerts = nil
+	stateIn.Compliance = nil
+	stateIn.Parent = nil
+	stateIn.NoncompliantChildren = nil
+	stateIn.Version = nil
+	stateIn.SchemaVersion = nil
+	stateIn.ReadingTimes = nil
+	// a deleted asset keeps its ID until it is purged
+	deleted, err := t.isDeleted(stub, assetID)
+	if err != nil {
+		return stateStub, false, err
+	}
+	if deleted {
+		return stateStub, false, errors.New("Asset " + assetID + " is deleted, it must be restored before it can be updated")
+	}
+	// the event is a JSON merge patch on the stored state, explicit nulls
+	// clear properties and nested objects merge member by member
+	patch, err := eventPatch(args[0])
+	if err != nil {
+		return stateStub, false, err
+	}
+	var stored interface{}
+	assetBytes, err := getAssetBytes(stub, assetID)
+	if err != nil {
+		return stateStub, false, err
+	}
+	if len(assetBytes) == 0 {
 		// This implies that this is a 'create' scenario
-		stateStub = stateIn // The record that goes into the stub is the one that cme in
+		created = true
 	} else {
 		// This is an update scenario
 		err = json.Unmarshal(assetBytes, &stateStub)
+		if err == nil {
+			err = json.Unmarshal(assetBytes, &stored)
+		}
 		if err != nil {
 			err = errors.New("Unable to unmarshal JSON data from stub")
-			return nil, err
-			// state is an empty instance of asset state
+			return stateStub, false, err
 		}
-		// Merge partial state updates
-		stateStub, err = t.mergePartialState(stateStub, stateIn)
+		priorMap, err := asArgsMap(stateStub)
 		if err != nil {
-			err = errors.New("Unable to merge state")
-			return nil, err
+			return stateStub, false, errors.New("Unable to convert prior state for rules: " + fmt.Sprint(err))
+		}
+		prior = &priorMap
+	}
+	// the event may only apply to the version its sender expects
+	err = checkExpectedVersion(args[0], assetID, assetVersion(stateStub))
+	if err != nil {
+		return stateStub, false, err
+	}
+	merged := mergePatch(stored, patch).(map[string]interface{})
+	merged["assetID"] = assetID
+	// the device timestamp belongs to the event that carried it
+	if _, found := patch["timestamp"]; !found {
+		delete(merged, "timestamp")
+	}
+	mergedJSON, err := json.Marshal(merged)
+	if err != nil {
+		return stateStub, false, errors.New("Unable to merge state: " + fmt.Sprint(err))
+	}
+	stateStub = AssetState{}
+	err = json.Unmarshal(mergedJSON, &stateStub)
+	if err != nil {
+		return stateStub, false, errors.New("Unable to merge state: " + fmt.Sprint(err))
+	}
+	// the asset type is given on create and fixed from then on, only an
+	// asset that predates the registry may be given one later
+	if created && stateIn.AssetType == nil {
+		return stateStub, false, errors.New("An assetType is mandatory to create an asset")
+	}
+	if prior != nil && stateIn.AssetType != nil {
+		if priorType, found := getObject(*prior, "assetType"); found && priorType != *stateIn.AssetType {
+			return stateStub, false, errors.New("The assetType of asset " + assetID + " cannot be changed")
 		}
 	}
-	stateJSON, err := json.Marshal(stateStub)
+	var assetType *AssetType
+	if stateStub.AssetType != nil {
+		assetType, err = getAssetType(*stateStub.AssetType)
+		if err != nil {
+			return stateStub, false, err
+		}
+		err = assetType.checkEventProperties(args[0])
+		if err != nil {
+			return stateStub, false, err
+		}
+	}
+	// record the transaction that produced this state
+	txnTime, err := txnTimestamp(stub)
 	if err != nil {
-		return nil, errors.New("Marshal failed for contract state" + fmt.Sprint(err))
+		return stateStub, false, errors.New("Unable to get transaction timestamp: " + fmt.Sprint(err))
 	}
-	// Get existing state from the stub
+	txnID := stub.GetTxID()
+	stateStub.TxnTimestamp = &txnTime
+	stateStub.TxnID = &txnID
 
-	// Write the new state to the ledger
-	err = stub.PutState(assetID, stateJSON)
+	// run the rules against the new state
+	stateStub, err = t.applyRules(stub, stateStub, stateIn, prior, applicableAlerts(assetType))
 	if err != nil {
-		err = errors.New("PUT ledger state failed: " + fmt.Sprint(err))
-		return nil, err
+		return stateStub, false, err
 	}
-	return nil, nil
+	// and take in the compliance of the assets it contains
+	err = t.rollUpChildren(stub, &stateStub)
+	if err != nil {
+		return stateStub, false, err
+	}
+	return stateStub, created, nil
 }
 
-/*********************************  internal: mergePartialState ****************************/
-func (t *SimpleChaincode) mergePartialState(oldState AssetState, newState AssetState) (AssetState, error) {
-
-	old := reflect.ValueOf(&oldState).Elem()
-	new := reflect.ValueOf(&newState).Elem()
+/*********************************  internal: applyRules ****************************/
 
-	for i := 0; i < old.NumField(); i++ {
-		oldOne := old.Field(i)
-		newOne := new.Field(i)
-		if !reflect.ValueOf(newOne.Interface()).IsNil() {
-			oldOne.Set(reflect.Value(newOne))
+// applyRules executes the alert rules applicable to the asset type against
+// the merged state and stores the resulting alert status and compliance in it
+func (t *SimpleChaincode) applyRules(stub shim.ChaincodeStubInterface, state AssetState, event AssetState, prior *ArgsMap, applicable AlertArrayInternal) (AssetState, error) {
+	stateMap, err := asArgsMap(state)
+	if err != nil {
+		return state, errors.New("Unable to convert state for rules: " + fmt.Sprint(err))
+	}
+	eventMap, err := asArgsMap(event)
+	if err != nil {
+		return state, errors.New("Unable to convert event for rules: " + fmt.Sprint(err))
+	}
+	alerts := newAlertStatus()
+	if state.Alerts != nil {
+		alerts = *state.Alerts
+	}
+	suppressed, err := t.activeSuppressions(stub, *state.AssetID, *state.TxnTimestamp)
+	if err != nil {
+		return state, err
+	}
+	ctx := ruleContext{event: &eventMap, prior: prior, applicable: applicable, suppressed: suppressed}
+	noncompliant, err := stateMap.executeRules(&ctx, &alerts)
+	if err != nil {
+		return state, errors.New("Rules execution failed: " + fmt.Sprint(err))
+	}
+	compliant := !noncompliant
+	state.Alerts = &alerts
+	state.Compliance = &compliant
+	// the next rates of the readings in this event are measured from now,
+	// a cleared reading leaves nothing to measure from
+	for _, r := range rateOfChangeRules {
+		if _, found := getObject(stateMap, r.property); !found {
+			delete(state.ReadingTimes, r.property)
+			continue
+		}
+		if _, found := getObject(eventMap, r.property); !found {
+			continue
+		}
+		if state.ReadingTimes == nil {
+			state.ReadingTimes = make(ReadingTimes)
 		}
+		state.ReadingTimes[r.property] = ReadingTime{Timestamp: state.Timestamp, TxnTimestamp: state.TxnTimestamp}
 	}
+	if len(state.ReadingTimes) == 0 {
+		state.ReadingTimes = nil
+	}
+	return state, nil
+}
+
+/*********************************  internal: mergePatch ****************************/
 
-	return oldState, nil
+// computedProperties are calculated by the contract, an event never patches them
+var computedProperties = []string{"txntimestamp", "txnuuid", "alerts", "compliant", "parent", "noncompliantChildren", "version", "schemaVersion", "readingTimes"}
+
+// eventPatch decodes an event as a JSON merge patch on the stored state,
+// without the computed properties
+func eventPatch(eventJSON string) (map[string]interface{}, error) {
+	var patch map[string]interface{}
+
+	err := json.Unmarshal([]byte(eventJSON), &patch)
+	if err != nil {
+		return nil, errors.New("Unable to unmarshal input JSON data")
+	}
+	for _, name := range computedProperties {
+		delete(patch, name)
+	}
+	// a precondition of the request, not a property of the state
+	delete(patch, "expectedVersion")
+	if value, found := patch["assetType"]; found && value == nil {
+		return nil, errors.New("The assetType of an asset cannot be cleared")
+	}
+	return patch, nil
 }
-// --------------------------------ALERTS-----------------------------------------
 
+// mergePatch applies a JSON merge patch as described in RFC 7386: objects
+// merge recursively, null removes a member and any other value replaces the
+// target
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	patchMap, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetMap, ok := target.(map[string]interface{})
+	if !ok {
+		targetMap = make(map[string]interface{})
+	}
+	for name, value := range patchMap {
+		if value == nil {
+			delete(targetMap, name)
+			continue
+		}
+		targetMap[name] = mergePatch(targetMap[name], value)
+	}
+	return targetMap
+}
+
+// --------------------------------ALERTS-----------------------------------------
 
 var AlertsName = map[int]string{
 	0: "OVERTEMP",
 	1: "overhum",
+	2: "TEMPRATE",
+	3: "HUMRATE",
+	4: "SEALTAMPER",
 }
 
 var AlertsValue = map[string]int32{
-	"OVERTEMP": 0,
-	"overhum": 1,
+	"OVERTEMP":   0,
+	"overhum":    1,
+	"TEMPRATE":   2,
+	"HUMRATE":    3,
+	"SEALTAMPER": 4,
 }
 
-
 func (x Alerts) String() string {
 	return AlertsName[int(x)]
 }
 
-
 type AlertNameArray []string
 
 type Alerts int32
 
 const (
-    // AlertsOVERTEMP the over temperature alert 
- 	AlertsOVERTEMP Alerts = 0
-    // AlertsSIZE is to be maintained always as 1 greater than the last alert, giving a size  
-	AlertsOVERHUM        Alerts = 1
-
-	AlertsSIZE.  Alerts = 2
+	// AlertsOVERTEMP the over temperature alert
+	AlertsOVERTEMP Alerts = 0
+	// AlertsOVERHUM the over humidity alert
+	AlertsOVERHUM Alerts = 1
+	// AlertsTEMPRATE the temperature rate of change alert
+	AlertsTEMPRATE Alerts = 2
+	// AlertsHUMRATE the humidity rate of change alert
+	AlertsHUMRATE Alerts = 3
+	// AlertsSEALTAMPER the broken seal alert
+	AlertsSEALTAMPER Alerts = 4
+
+	// AlertsSIZE is to be maintained always as 1 greater than the last alert, giving a size
+	AlertsSIZE Alerts = 5
 )
 
 type AlertArrayInternal [AlertsSIZE]bool
 
+// AlertsLatched marks the alerts that the rules can raise but never clear,
+// they stay active until an inspector clears them with clearLatchedAlert
+var AlertsLatched = AlertArrayInternal{
+	AlertsSEALTAMPER: true,
+}
+
 var NOALERTSACTIVEINTERNAL = AlertArrayInternal{}
 
 var NOALERTSACTIVE = AlertNameArray{}
 
 type AlertStatus struct {
-    Active  AlertNameArray  `json:"active"`
-    Raised  AlertNameArray  `json:"raised"`
-    Cleared AlertNameArray  `json:"cleared"`
+	Active  AlertNameArray `json:"active"`
+	Raised  AlertNameArray `json:"raised"`
+	Cleared AlertNameArray `json:"cleared"`
 }
 type AlertStatusInternal struct {
-    Active  AlertArrayInternal  
-    Raised  AlertArrayInternal  
-    Cleared AlertArrayInternal  
-}
-
-func (a *AlertStatus) asAlertStatusInternal() (AlertStatusInternal) {
-    var aOut = AlertStatusInternal{}
-    for i := range a.Active {
-        aOut.Active[AlertsValue[a.Active[i]]] = true
-    }
-    for i := range a.Raised {
-        aOut.Raised[AlertsValue[a.Raised[i]]] = true
-    }
-    for i := range a.Cleared {
-        aOut.Cleared[AlertsValue[a.Cleared[i]]] = true
-    }
-    return aOut
-}
-
-func (a *AlertStatusInternal) asAlertStatus() (AlertStatus) {
-    var aOut = newAlertStatus()
-    for i := range a.Active {
-        if a.Active[i] {
-            aOut.Active = append(aOut.Active, AlertsName[i])
-        }
-    }
-    for i := range a.Raised {
-        if a.Raised[i] {
-            aOut.Raised = append(aOut.Raised, AlertsName[i])
-        }
-    }
-    for i := range a.Cleared {
-        if a.Cleared[i] {
-            aOut.Cleared = append(aOut.Cleared, AlertsName[i])
-        }
-    }
-    return aOut
-}
-
-func (a *AlertStatusInternal) clearRaisedAndClearedStatus () {
-    for i := range a.Active {
-        if a.Active[i] {
-            a.Raised[i] = false
-        } else {
-            a.Cleared[i] = false
-        }
-    }
-}
-func (a *AlertStatusInternal) raiseAlert (alert Alerts) {
-    if a.Active[alert] {
-        // already raised
-        // this is tricky, should not say this event raised an
-        // active alarm, as it makes it much more difficult to track
-        // the exact moments of transition
-        a.Active[alert] = true
-        a.Raised[alert] = false
-        a.Cleared[alert] = false
-    } else {
-        // raising it
-        a.Active[alert] = true
-        a.Raised[alert] = true
-        a.Cleared[alert] = false
-    }
-}
-
-func (a *AlertStatusInternal) clearAlert (alert Alerts) {
-    if a.Active[alert] {
-        // clearing alert
-        a.Active[alert] = false
-        a.Raised[alert] = false
-        a.Cleared[alert] = true
-    } else {
-        // was not active
-        a.Active[alert] = false
-        a.Raised[alert] = false
-        // this is tricky, should not say this event cleared an
-        // inactive alarm, as it makes it much more difficult to track
-        //  the exact moments of transition
-        a.Cleared[alert] = false
-    }
-}
-
-func newAlertStatus() (AlertStatus) {
-    var a AlertStatus
-    a.Active = make([]string, 0, AlertsSIZE)
-    a.Raised = make([]string, 0, AlertsSIZE)
-    a.Cleared = make([]string, 0, AlertsSIZE)
-    return a
-}
-
-func (arr *AlertNameArray) copyFrom (s []interface{}) {
-    // a conversion like this must assert type at every level
-    for i := 0; i < len(s); i++ {
-        *arr = append(*arr, s[i].(string))
-    }
+	Active  AlertArrayInternal
+	Raised  AlertArrayInternal
+	Cleared AlertArrayInternal
+}
+
+func (a *AlertStatus) asAlertStatusInternal() AlertStatusInternal {
+	var aOut = AlertStatusInternal{}
+	for i := range a.Active {
+		aOut.Active[AlertsValue[a.Active[i]]] = true
+	}
+	for i := range a.Raised {
+		aOut.Raised[AlertsValue[a.Raised[i]]] = true
+	}
+	for i := range a.Cleared {
+		aOut.Cleared[AlertsValue[a.Cleared[i]]] = true
+	}
+	return aOut
+}
+
+func (a *AlertStatusInternal) asAlertStatus() AlertStatus {
+	var aOut = newAlertStatus()
+	for i := range a.Active {
+		if a.Active[i] {
+			aOut.Active = append(aOut.Active, AlertsName[i])
+		}
+	}
+	for i := range a.Raised {
+		if a.Raised[i] {
+			aOut.Raised = append(aOut.Raised, AlertsName[i])
+		}
+	}
+	for i := range a.Cleared {
+		if a.Cleared[i] {
+			aOut.Cleared = append(aOut.Cleared, AlertsName[i])
+		}
+	}
+	return aOut
+}
+
+func (a *AlertStatusInternal) clearRaisedAndClearedStatus() {
+	for i := range a.Active {
+		if a.Active[i] {
+			a.Raised[i] = false
+		} else {
+			a.Cleared[i] = false
+		}
+	}
+}
+func (a *AlertStatusInternal) raiseAlert(alert Alerts) {
+	if a.Active[alert] {
+		// already raised
+		// this is tricky, should not say this event raised an
+		// active alarm, as it makes it much more difficult to track
+		// the exact moments of transition
+		a.Active[alert] = true
+		a.Raised[alert] = false
+		a.Cleared[alert] = false
+	} else {
+		// raising it
+		a.Active[alert] = true
+		a.Raised[alert] = true
+		a.Cleared[alert] = false
+	}
+}
+
+func (a *AlertStatusInternal) clearAlert(alert Alerts) {
+	if a.Active[alert] && AlertsLatched[alert] {
+		// latched, stays active until cleared by an inspector
+		a.Raised[alert] = false
+		a.Cleared[alert] = false
+		return
+	}
+	if a.Active[alert] {
+		// clearing alert
+		a.Active[alert] = false
+		a.Raised[alert] = false
+		a.Cleared[alert] = true
+	} else {
+		// was not active
+		a.Active[alert] = false
+		a.Raised[alert] = false
+		// this is tricky, should not say this event cleared an
+		// inactive alarm, as it makes it much more difficult to track
+		//  the exact moments of transition
+		a.Cleared[alert] = false
+	}
+}
+
+// suppressAlerts takes back any raise of a suppressed alert, so that an
+// alert that was not active before this event stays inactive
+func (a *AlertStatusInternal) suppressAlerts(suppressed AlertArrayInternal) {
+	for i := range suppressed {
+		if suppressed[i] && a.Raised[i] {
+			a.Active[i] = false
+			a.Raised[i] = false
+		}
+	}
+}
+
+func newAlertStatus() AlertStatus {
+	var a AlertStatus
+	a.Active = make([]string, 0, AlertsSIZE)
+	a.Raised = make([]string, 0, AlertsSIZE)
+	a.Cleared = make([]string, 0, AlertsSIZE)
+	return a
+}
+
+func (arr *AlertNameArray) copyFrom(s []interface{}) {
+	// a conversion like this must assert type at every level
+	for i := 0; i < len(s); i++ {
+		*arr = append(*arr, s[i].(string))
+	}
 }
 
 // NoAlertsActive returns true when no alerts are active in the asset's status at this time
-func (arr *AlertStatusInternal) NoAlertsActive() (bool) {
-    return (arr.Active == NOALERTSACTIVEINTERNAL)
+func (arr *AlertStatusInternal) NoAlertsActive() bool {
+	return (arr.Active == NOALERTSACTIVEINTERNAL)
 }
 
 // AllClear returns true when no alerts are active, raised or cleared in the asset's status at this time
-func (arr *AlertStatusInternal) AllClear() (bool) {
-    return  (arr.Active == NOALERTSACTIVEINTERNAL) &&
-            (arr.Raised == NOALERTSACTIVEINTERNAL) &&
-            (arr.Cleared == NOALERTSACTIVEINTERNAL) 
+func (arr *AlertStatusInternal) AllClear() bool {
+	return (arr.Active == NOALERTSACTIVEINTERNAL) &&
+		(arr.Raised == NOALERTSACTIVEINTERNAL) &&
+		(arr.Cleared == NOALERTSACTIVEINTERNAL)
 }
 
 // NoAlertsActive returns true when no alerts are active in the asset's status at this time
-func (a *AlertStatus) NoAlertsActive() (bool) {
-    return len(a.Active) == 0
+func (a *AlertStatus) NoAlertsActive() bool {
+	return len(a.Active) == 0
 }
 
 // AllClear returns true when no alerts are active, raised or cleared in the asset's status at this time
-func (a *AlertStatus) AllClear() (bool) {
-    return  len(a.Active) == 0 &&
-            len(a.Raised) == 0 &&
-            len(a.Cleared) == 0 
+func (a *AlertStatus) AllClear() bool {
+	return len(a.Active) == 0 &&
+		len(a.Raised) == 0 &&
+		len(a.Cleared) == 0
 }
 
 //------------------------------- rules ---------------------------------
 
-func (a *ArgsMap) executeRules(alerts *AlertStatus) (bool, error) {
-    log.Debugf("Executing rules input: %+v", *alerts)
-    // transform external to internal for easy alert status processing
-    var internal = (*alerts).asAlertStatusInternal()
-
-    internal.clearRaisedAndClearedStatus()
-
-    // ------ validation rules
-    // rule 1 -- test validation failure
-    err := internal.testValidationRule(a)
-    // return value is not used, return true, which means noncompliant
-    if err != nil {return true, err}
-    // rule 2 -- ???
-
-    // ------ alert rules
-    // rule 1 -- overtemp
-    err = internal.overTempRule(a)
-    if err != nil {return true, err}
-    // rule 2 -- overhum
-    err = internal.overHumRule(a)
-    if err != nil {return true, err}
-
-    // transform for external consumption
-    *alerts = internal.asAlertStatus()
-    log.Debugf("Executing rules output: %+v", *alerts)
-
-    // set compliance true means out of compliance
-    compliant, err := internal.calculateContractCompliance(a) 
-    if err != nil {return true, err} 
-    // returns true if anything at all is active (i.e. NOT compliant)
-    return !compliant, nil
-}
-
-func (alerts *AlertStatusInternal) testValidationRule (a *ArgsMap) error {
-    tbytes, found := getObject(*a, "testValidation")
-    if found {
-        t, found := tbytes.(bool)
-        if found {
-            if t {
-                err := errors.New("testValidation property found and is true") 
-                return err
-            }
-        }
-    }
-    return nil
-}
-
-
-func (alerts *AlertStatusInternal) overTempRule (a *ArgsMap) error {
-    const temperatureThreshold  float64 = 60 // (inclusive good value)
-
-    tbytes, found := getObject(*a, "MaxTemperature")
-    if found {
-        t, found := tbytes.(float64)
-        if found {
-            if t > temperatureThreshold {
-                alerts.raiseAlert(AlertsOVERTEMP)
-                return nil
-            }
-        } else {
-            log.Warning("overTempRule: temperature not type JSON Number, alert status not changed")
-            // do nothing to the alerts status
-            return nil 
-        }
-    }
-    alerts.clearAlert(AlertsOVERTEMP)
-    return nil
-}
-
-func (alerts *AlertStatusInternal) overHumRule (a *ArgsMap) error {
-    const HumidityThreshold  float64 = 80 // (inclusive good value)
-
-    tbytes, found := getObject(*a, "MaxHumidity")
-    if found {
-        t, found := tbytes.(float64)
-        if found {
-            if t > HumidityThreshold {
-                alerts.raiseAlert(AlertsOVERHUM)
-                return nil
-            }
-        } else {
-            log.Warning("overHumRule: humidity not type JSON Number, alert status not changed")
-            // do nothing to the alerts status
-            return nil 
-        }
-    }
-    alerts.clearAlert(AlertsOVERHUM)
-    return nil
-}
-
-func (alerts *AlertStatusInternal) calculateContractCompliance (a *ArgsMap) (bool, error) {
-    // a simplistic calculation for this particular contract, but has access
-    // to the entire state object and can thus have at it
-    // compliant is no alerts active
-    return alerts.NoAlertsActive(), nil
-    // NOTE: There could still a "cleared" alert, so don't go
-    //       deleting the alerts from the ledger just on this status.
-}
-
-   
-//-----------------------Main---------------------------------
-func main() {
-    err := shim.Start(new(SimpleChaincode))
-    if err != nil {
-        fmt.Printf("Error starting Simple chaincode: %s", err)
-    }
+// ruleContext holds what the rules may consult besides the merged state
+type ruleContext struct {
+	event      *ArgsMap           // the incoming partial state
+	prior      *ArgsMap           // state stored before this event, nil on create
+	applicable AlertArrayInternal // alerts that the rules of the asset type may raise
+	suppressed AlertArrayInternal // alerts that this event may not raise
 }
 
+// executeRules runs the rules against the merged asset state a
+func (a *ArgsMap) executeRules(ctx *ruleContext, alerts *AlertStatus) (bool, error) {
+	log.Debugf("Executing rules input: %+v", *alerts)
+	// transform external to internal for easy alert status processing
+	var internal = (*alerts).asAlertStatusInternal()
+
+	internal.clearRaisedAndClearedStatus()
+
+	// ------ validation rules
+	// rule 1 -- test validation failure
+	err := internal.testValidationRule(a)
+	// return value is not used, return true, which means noncompliant
+	if err != nil {
+		return true, err
+	}
+	// rule 2 -- ???
+
+	// ------ alert rules
+	// rule 1 -- conditions on the state, overtemp and overhum
+	for _, r := range alertConditions {
+		if !ctx.applicable[r.alert] {
+			// not a rule of this asset type
+			internal.clearAlert(r.alert)
+			continue
+		}
+		err = internal.conditionRule(r, a)
+		if err != nil {
+			return true, err
+		}
+	}
+	// rule 2 -- rates of change against the prior state
+	for _, r := range rateOfChangeRules {
+		if !ctx.applicable[r.alert] {
+			// not a rule of this asset type
+			internal.clearAlert(r.alert)
+			continue
+		}
+		err = internal.rateOfChangeRule(r, a, ctx.event, ctx.prior)
+		if err != nil {
+			return true, err
+		}
+	}
+
+	// ------ suppressions
+	internal.suppressAlerts(ctx.suppressed)
 
+	// transform for external consumption
+	*alerts = internal.asAlertStatus()
+	log.Debugf("Executing rules output: %+v", *alerts)
+
+	// set compliance true means out of compliance
+	compliant, err := internal.calculateContractCompliance(a, ctx.suppressed)
+	if err != nil {
+		return true, err
+	}
+	// returns true if anything at all is active (i.e. NOT compliant)
+	return !compliant, nil
+}
+
+func (alerts *AlertStatusInternal) testValidationRule(a *ArgsMap) error {
+	tbytes, found := getObject(*a, "testValidation")
+	if found {
+		t, found := tbytes.(bool)
+		if found {
+			if t {
+				err := errors.New("testValidation property found and is true")
+				return err
+			}
+		}
+	}
+	return nil
+}
 
+// alertCondition raises its alert while the condition holds for the asset
+// state and clears it otherwise, see expressions.go for the condition syntax
+type alertCondition struct {
+	alert     Alerts
+	condition string
+	parsed    *Condition // parsed once when the rule is defined
+}
+
+// newAlertCondition parses the condition of a rule, a rule that does not
+// parse is a bug of the contract so the contract does not start
+func newAlertCondition(alert Alerts, condition string) alertCondition {
+	c, err := ParseCondition(condition)
+	if err != nil {
+		panic(fmt.Sprintf("alert condition for %s: %v", alert, err))
+	}
+	return alertCondition{alert, condition, c}
+}
+
+var alertConditions = []alertCondition{
+	// (inclusive good values) cargo in the tropics is held to a lower limit
+	newAlertCondition(AlertsOVERTEMP, `maxTemperature > 60 OR (maxTemperature > 45 AND inside(location, "tropical"))`),
+	newAlertCondition(AlertsOVERHUM, "maxHumidity > 80"), // (inclusive good value)
+	newAlertCondition(AlertsSEALTAMPER, "sealBroken == true"),
+}
+
+func (alerts *AlertStatusInternal) conditionRule(r alertCondition, a *ArgsMap) error {
+	holds, err := r.parsed.Evaluate(a)
+	if err != nil {
+		log.Warningf("conditionRule %s: %s, alert status not changed", r.alert, err)
+		// do nothing to the alerts status
+		return nil
+	}
+	if holds {
+		alerts.raiseAlert(r.alert)
+		return nil
+	}
+	alerts.clearAlert(r.alert)
+	return nil
+}
+
+// rateOfChange configures a rule that compares a reading to the one in the
+// prior state and alerts when it moves faster than maxPerHour in either direction
+type rateOfChange struct {
+	alert      Alerts
+	property   string  // qualified name of the reading
+	maxPerHour float64 // (inclusive good value)
+}
+
+var rateOfChangeRules = []rateOfChange{
+	{AlertsTEMPRATE, "maxTemperature", 5},
+	{AlertsHUMRATE, "maxHumidity", 10},
+}
+
+func (alerts *AlertStatusInternal) rateOfChangeRule(r rateOfChange, a *ArgsMap, event *ArgsMap, prior *ArgsMap) error {
+	if prior == nil {
+		// first reading for this asset, nothing to compare against
+		alerts.clearAlert(r.alert)
+		return nil
+	}
+	if _, found := getObject(*event, r.property); !found {
+		// no new reading in this event, the stored one is not a measurement
+		// at this event's time so the alert status is not changed
+		return nil
+	}
+	tbytes, found := getObject(*a, r.property)
+	if !found {
+		return nil
+	}
+	t, ok := tbytes.(float64)
+	if !ok {
+		log.Warningf("rateOfChangeRule: %s not type JSON Number, alert status not changed", r.property)
+		return nil
+	}
+	pbytes, found := getObject(*prior, r.property)
+	if !found {
+		// first reading of this kind, nothing to compare against
+		alerts.clearAlert(r.alert)
+		return nil
+	}
+	p, ok := pbytes.(float64)
+	if !ok {
+		log.Warningf("rateOfChangeRule: prior %s not type JSON Number, alert status not changed", r.property)
+		return nil
+	}
+	// measured from when the prior reading was taken, not from the last
+	// event, which may have carried other readings only. States written
+	// before readings were timed only know the time of their last event.
+	var taken interface{} = *prior
+	if times, found := getObject(*prior, "readingTimes."+r.property); found {
+		taken = times
+	}
+	hours, ok := elapsedHours(taken, a)
+	if !ok {
+		log.Warningf("rateOfChangeRule: no usable timestamps for %s, alert status not changed", r.property)
+		return nil
+	}
+	if math.Abs(t-p)/hours > r.maxPerHour {
+		alerts.raiseAlert(r.alert)
+		return nil
+	}
+	alerts.clearAlert(r.alert)
+	return nil
+}
+
+// elapsedHours returns the time between two states or reading times.
+// Device timestamps are used when both carry one, otherwise the
+// transaction timestamps are compared, so that the two clocks are never
+// mixed.
+func elapsedHours(from interface{}, to *ArgsMap) (float64, bool) {
+	for _, qname := range []string{"timestamp", "txntimestamp"} {
+		start, found := getTime(from, qname)
+		if !found {
+			continue
+		}
+		end, found := getTime(*to, qname)
+		if !found {
+			continue
+		}
+		elapsed := end.Sub(start).Hours()
+		if elapsed <= 0 {
+			return 0, false
+		}
+		return elapsed, true
+	}
+	return 0, false
+}
+
+func (alerts *AlertStatusInternal) calculateContractCompliance(a *ArgsMap, suppressed AlertArrayInternal) (bool, error) {
+	// a simplistic calculation for this particular contract, but has access
+	// to the entire state object and can thus have at it
+	// compliant is no alerts active, other than suppressed ones
+	for i := range alerts.Active {
+		if alerts.Active[i] && !suppressed[i] {
+			return false, nil
+		}
+	}
+	return true, nil
+	// NOTE: There could still a "cleared" alert, so don't go
+	//       deleting the alerts from the ledger just on this status.
+}
+
+//------------------------------- utilities ---------------------------------
+
+// ArgsMap is a generic map of JSON properties, used by the rules
+type ArgsMap map[string]interface{}
+
+// asArgsMap converts any JSON encodable object into an ArgsMap
+func asArgsMap(obj interface{}) (ArgsMap, error) {
+	var a ArgsMap
+	objJSON, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(objJSON, &a)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// getObject finds an object by its qualified name, which looks like
+// "location.latitude" as one example. Property names are matched without
+// regard to case so that rules can use the Go field names.
+func getObject(objIn interface{}, qname string) (interface{}, bool) {
+	var obj interface{} = objIn
+	for _, name := range strings.Split(qname, ".") {
+		var m map[string]interface{}
+		switch o := obj.(type) {
+		case ArgsMap:
+			m = o
+		case map[string]interface{}:
+			m = o
+		default:
+			return nil, false
+		}
+		v, found := m[name]
+		if !found {
+			for k := range m {
+				if strings.EqualFold(k, name) {
+					v, found = m[k], true
+					break
+				}
+			}
+		}
+		if !found {
+			return nil, false
+		}
+		obj = v
+	}
+	return obj, true
+}
+
+// getTime finds a timestamp string by its qualified name and parses it
+func getTime(objIn interface{}, qname string) (time.Time, bool) {
+	tbytes, found := getObject(objIn, qname)
+	if !found {
+		return time.Time{}, false
+	}
+	ts, ok := tbytes.(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// getKeysWithPrefix returns the keys and values stored under a key prefix
+func getKeysWithPrefix(stub shim.ChaincodeStubInterface, prefix string) ([]string, [][]byte, error) {
+	var keys = make([]string, 0)
+	var values = make([][]byte, 0)
+
+	// the end key is the prefix with its last character incremented
+	endKey := prefix[:len(prefix)-1] + string(prefix[len(prefix)-1]+1)
+	iter, err := stub.GetStateByRange(prefix, endKey)
+	if err != nil {
+		return nil, nil, errors.New("Range query failed: " + fmt.Sprint(err))
+	}
+	defer iter.Close()
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, nil, errors.New("Range query iteration failed: " + fmt.Sprint(err))
+		}
+		// some ledgers include the end key
+		if !strings.HasPrefix(kv.Key, prefix) {
+			continue
+		}
+		keys = append(keys, kv.Key)
+		values = append(values, kv.Value)
+	}
+	return keys, values, nil
+}
+
+// txnTimestamp returns the transaction timestamp as an RFC3339 string
+func txnTimestamp(stub shim.ChaincodeStubInterface) (string, error) {
+	ts, err := stub.GetTxTimestamp()
+	if err != nil {
+		return "", err
+	}
+	return time.Unix(ts.Seconds, int64(ts.Nanos)).UTC().Format(time.RFC3339Nano), nil
+}
+
+// callerIdentity returns the common name in the certificate of the submitter
+func callerIdentity(stub shim.ChaincodeStubInterface) (string, error) {
+	cert, err := cid.GetX509Certificate(stub)
+	if err != nil {
+		return "", errors.New("Unable to get caller certificate: " + fmt.Sprint(err))
+	}
+	if cert == nil {
+		return "", errors.New("Unable to identify caller, no certificate was supplied")
+	}
+	if cert.Subject.CommonName == "" {
+		return "", errors.New("Caller certificate carries no common name")
+	}
+	return cert.Subject.CommonName, nil
+}
+
+// logger writes to the log of the chaincode container, which the shim no
+// longer provides. Debug output is on when CORE_CHAINCODE_LOGGING_LEVEL is
+// debug.
+type logger struct {
+	out   *golog.Logger
+	debug bool
+}
+
+func newLogger(name string) *logger {
+	return &logger{
+		out:   golog.New(os.Stderr, name+" ", golog.LstdFlags),
+		debug: strings.EqualFold(os.Getenv("CORE_CHAINCODE_LOGGING_LEVEL"), "debug"),
+	}
+}
+
+// Debugf logs a message for debugging
+func (l *logger) Debugf(format string, args ...interface{}) {
+	if l.debug {
+		l.out.Printf("DEBUG "+format, args...)
+	}
+}
+
+// Warningf logs a message about something that went wrong but was tolerated
+func (l *logger) Warningf(format string, args ...interface{}) {
+	l.out.Printf("WARNING "+format, args...)
+}
@@ -0,0 +1,558 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInit(t *testing.T) {
+	version := `{"version":"` + MYVERSION + `"}`
+	trade := `{"tradeID":"` + TRADEID + `"}`
+	tests := []struct {
+		name    string
+		roles   string
+		args    []string
+		wantErr string
+	}{
+		{"instantiates", ADMINROLE, []string{version, trade}, ""},
+		{"admin only", CARRIERROLE, []string{version, trade}, "Permission denied: init requires the admin role"},
+		{"missing trade", ADMINROLE, []string{version}, "init expects 2 arguments"},
+		{"version not JSON", ADMINROLE, []string{"1.1", trade}, "Version argument unmarshal failed"},
+		{"other version", ADMINROLE, []string{`{"version":"0.9"}`, trade}, "must match version argument: 0.9"},
+		{"trade not JSON", ADMINROLE, []string{version, "0476219"}, "Trade id argument unmarshal failed"},
+		{"other trade", ADMINROLE, []string{version, `{"tradeID":"1"}`}, "must match trade id: 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMockStub(t).as("admin", tt.roles)
+			wantError(t, m.init(tt.args...), tt.wantErr)
+			if tt.wantErr != "" {
+				if len(m.state) != 0 {
+					t.Fatalf("a failed init wrote to the ledger: %v", m.state)
+				}
+				return
+			}
+			var contract ContractState
+			var tradeState TradeState
+			json.Unmarshal(m.mustQuery("readContractState"), &contract)
+			json.Unmarshal(m.mustQuery("readTradeState"), &tradeState)
+			if contract.Version != MYVERSION || contract.Status != STATUSACTIVE {
+				t.Fatalf("contract state %+v, expected version %s and active", contract, MYVERSION)
+			}
+			if tradeState.TradeID != TRADEID {
+				t.Fatalf("trade state %+v, expected trade %s", tradeState, TRADEID)
+			}
+		})
+	}
+}
+
+func TestCreateAsset(t *testing.T) {
+	tests := []struct {
+		name    string
+		event   string
+		wantErr string
+	}{
+		{"reefer", `{"assetID":"R1","assetType":"ReeferContainer","carrier":"admin","maxTemperature":4,"location":{"latitude":51.9,"longitude":4.1}}`, ""},
+		{"vessel with extension", `{"assetID":"V1","assetType":"Vessel","extension":{"imo":"9321483"}}`, ""},
+		{"no asset type", `{"assetID":"R1","maxTemperature":4}`, "args[0].assetType: is required"},
+		{"unknown asset type", `{"assetID":"R1","assetType":"Barge"}`, "args[0].assetType: Barge is not one of CrudeTank, ReeferContainer, Vessel"},
+		{"undeclared property", `{"assetID":"T1","assetType":"CrudeTank","maxHumidity":40}`, "Property maxHumidity is not defined for asset type CrudeTank"},
+		{"blank asset ID", `{"assetID":"  ","assetType":"Vessel"}`, "AssetID not passed"},
+		{"no asset ID", `{"assetType":"Vessel"}`, "assetID"},
+		{"not JSON", `reefer`, "not a JSON encoded value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestContract(t)
+			_, err := m.invoke("createAsset", tt.event)
+			wantError(t, err, tt.wantErr)
+			if tt.wantErr != "" {
+				return
+			}
+			var event AssetState
+			json.Unmarshal([]byte(tt.event), &event)
+			state := m.asset(*event.AssetID)
+			if state.TxnID == nil || *state.TxnID != "tx0002" {
+				t.Fatalf("txnuuid %v, expected the ID of the create transaction", state.TxnID)
+			}
+			created := mockStartTime.Add(mockTxInterval).Format(time.RFC3339Nano)
+			if state.TxnTimestamp == nil || *state.TxnTimestamp != created {
+				t.Fatalf("txntimestamp %v, expected %s", state.TxnTimestamp, created)
+			}
+			if state.Version == nil || *state.Version != 1 {
+				t.Fatalf("version %v, expected 1", state.Version)
+			}
+			if state.SchemaVersion == nil || *state.SchemaVersion != MYVERSION {
+				t.Fatalf("schemaVersion %v, expected %s", state.SchemaVersion, MYVERSION)
+			}
+			if state.Compliance == nil || !*state.Compliance || state.Alerts == nil || !state.Alerts.AllClear() {
+				t.Fatalf("a new asset within its limits must be compliant without alerts: %+v", state)
+			}
+			if !reflect.DeepEqual(state.Extension, event.Extension) {
+				t.Fatalf("extension %v, expected %v", state.Extension, event.Extension)
+			}
+		})
+	}
+}
+
+func TestUpdateAssetMergesEvents(t *testing.T) {
+	m := newTestContract(t)
+	m.mustInvoke("createAsset", `{"assetID":"R1","assetType":"ReeferContainer","carrier":"admin","maxTemperature":4,"location":{"latitude":51.9,"longitude":4.1},"extension":{"booking":"B1","seal":{"id":"S1"}}}`)
+	steps := []struct {
+		name    string
+		event   string
+		wantErr string
+		check   func(s AssetState) bool
+	}{
+		{"reading", `{"assetID":"R1","maxTemperature":5}`, "",
+			func(s AssetState) bool {
+				return *s.MaxTemperature == 5 && *s.Location.Latitude == 51.9 && *s.Version == 2
+			}},
+		{"nested members merge", `{"assetID":"R1","location":{"latitude":52.1}}`, "",
+			func(s AssetState) bool { return *s.Location.Latitude == 52.1 && *s.Location.Longitude == 4.1 }},
+		{"null clears", `{"assetID":"R1","extension":{"seal":null}}`, "",
+			func(s AssetState) bool { return reflect.DeepEqual(s.Extension, ArgsMap{"booking": "B1"}) }},
+		{"device timestamp", `{"assetID":"R1","timestamp":"2017-03-20T09:30:00Z"}`, "",
+			func(s AssetState) bool { return s.Timestamp != nil && *s.Timestamp == "2017-03-20T09:30:00Z" }},
+		{"timestamp belongs to its event", `{"assetID":"R1","maxHumidity":60}`, "",
+			func(s AssetState) bool { return s.Timestamp == nil && *s.MaxHumidity == 60 }},
+		{"expected version not stored", `{"assetID":"R1","expectedVersion":6,"maxTemperature":5}`, "",
+			func(s AssetState) bool {
+				return *s.Version == 7 && !strings.Contains(string(m.state["R1"]), "expectedVersion")
+			}},
+		{"asset type fixed", `{"assetID":"R1","assetType":"CrudeTank"}`, "The assetType of asset R1 cannot be changed", nil},
+		{"asset type kept", `{"assetID":"R1","assetType":null}`, "args[0].assetType: expecting string, got null", nil},
+		{"asset ID trimmed", `{"assetID":" R1 ","maxTemperature":6}`, "",
+			func(s AssetState) bool { return *s.MaxTemperature == 6 && *s.Version == 8 }},
+	}
+	for _, step := range steps {
+		_, err := m.invoke("updateAsset", step.event)
+		if step.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), step.wantErr) {
+				t.Fatalf("%s: expected an error containing %q, got %v", step.name, step.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if state := m.asset("R1"); !step.check(state) {
+			stateJSON, _ := json.Marshal(state)
+			t.Fatalf("%s: unexpected state %s", step.name, stateJSON)
+		}
+	}
+}
+
+func TestReadAsset(t *testing.T) {
+	m := newTestContract(t)
+	m.mustInvoke("createAsset", `{"assetID":"V1","assetType":"Vessel"}`)
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"exists", []string{`{"assetID":"V1"}`}, ""},
+		{"unknown asset", []string{`{"assetID":"V2"}`}, "Unable to get asset state from ledger"},
+		{"blank asset ID", []string{`{"assetID":""}`}, "Asset does not exist!"},
+		{"no arguments", nil, "Invalid arguments for readAsset"},
+		{"two arguments", []string{`{"assetID":"V1"}`, `{"assetID":"V1"}`}, "Invalid arguments for readAsset"},
+		{"unknown member", []string{`{"assetID":"V1","imo":"9321483"}`}, "Invalid arguments for readAsset"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := m.query("readAsset", tt.args...)
+			wantError(t, err, tt.wantErr)
+			if tt.wantErr == "" && !json.Valid(payload) {
+				t.Fatalf("readAsset returned %q", payload)
+			}
+		})
+	}
+}
+
+func TestPreviewUpdate(t *testing.T) {
+	m := newTestContract(t)
+	m.mustInvoke("createAsset", `{"assetID":"R1","assetType":"ReeferContainer","maxTemperature":20}`)
+	stored := m.state["R1"]
+
+	var preview UpdatePreview
+	json.Unmarshal(m.mustQuery("previewUpdate", `{"assetID":"R1","maxTemperature":70}`), &preview)
+	if preview.Created {
+		t.Fatal("the preview of an update claims to create the asset")
+	}
+	if got := alertNames(preview.Raised); !reflect.DeepEqual(got, []string{"OVERTEMP", "TEMPRATE"}) {
+		t.Fatalf("preview raised %v, expected OVERTEMP and TEMPRATE", got)
+	}
+	if *preview.State.MaxTemperature != 70 || *preview.State.Version != 2 || *preview.State.Compliance {
+		t.Fatalf("unexpected preview state %+v", preview.State)
+	}
+	if string(m.state["R1"]) != string(stored) {
+		t.Fatal("previewUpdate changed the stored state")
+	}
+
+	json.Unmarshal(m.mustQuery("previewUpdate", `{"assetID":"R2","assetType":"CrudeTank","sealBroken":true}`), &preview)
+	if !preview.Created || *preview.State.Version != 1 || !reflect.DeepEqual(alertNames(preview.Raised), []string{"SEALTAMPER"}) {
+		t.Fatalf("unexpected preview of a create %+v", preview)
+	}
+	if _, found := m.state["R2"]; found {
+		t.Fatal("previewUpdate created the asset")
+	}
+	_, err := m.query("previewUpdate", `{"assetID":"R3","maxTemperature":4}`)
+	wantError(t, err, "An assetType is mandatory to create an asset")
+}
+
+func TestStateQueries(t *testing.T) {
+	tests := []struct {
+		function string
+		args     []string
+		wantKey  string
+		wantErr  string
+	}{
+		{"readContractState", nil, "version", ""},
+		{"readTradeState", nil, "tradeID", ""},
+		{"readAssetSamples", nil, "event", ""},
+		{"readAssetSchemas", nil, "API", ""},
+		{"readContractState", []string{"{}"}, "", "Too many arguments. Expecting none."},
+		{"readTradeState", []string{"{}"}, "", "Invalid arguments for readTradeState"},
+	}
+	m := newTestContract(t)
+	for _, tt := range tests {
+		t.Run(tt.function, func(t *testing.T) {
+			var result map[string]interface{}
+
+			payload, err := m.query(tt.function, tt.args...)
+			wantError(t, err, tt.wantErr)
+			if tt.wantErr != "" {
+				return
+			}
+			err = json.Unmarshal(payload, &result)
+			if err != nil {
+				t.Fatalf("%s returned invalid JSON: %v", tt.function, err)
+			}
+			if _, found := result[tt.wantKey]; !found {
+				t.Fatalf("%s returned no %s: %s", tt.function, tt.wantKey, payload)
+			}
+		})
+	}
+}
+
+func TestStateQueriesBeforeInit(t *testing.T) {
+	m := newMockStub(t)
+	for _, function := range []string{"readContractState", "readTradeState"} {
+		_, err := m.query(function)
+		wantError(t, err, "Unable to get")
+	}
+}
+
+func TestValidateInput(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantID  string
+		wantErr string
+	}{
+		{"asset ID", []string{`{"assetID":"R1"}`}, "R1", ""},
+		{"trimmed", []string{`{"assetID":"  R1\t"}`}, "R1", ""},
+		{"with state", []string{`{"assetID":"R1","maxTemperature":4}`}, "R1", ""},
+		{"blank", []string{`{"assetID":"   "}`}, "", "AssetID not passed"},
+		{"missing", []string{`{"maxTemperature":4}`}, "", "Asset id is mandatory in the input JSON data"},
+		{"null", []string{`{"assetID":null}`}, "", "Asset id is mandatory in the input JSON data"},
+		{"not a string", []string{`{"assetID":7}`}, "", "Unable to unmarshal input JSON data"},
+		{"not JSON", []string{`R1`}, "", "Unable to unmarshal input JSON data"},
+		{"no arguments", nil, "", "Incorrect number of arguments"},
+		{"two arguments", []string{`{"assetID":"R1"}`, `{"assetID":"R2"}`}, "", "Incorrect number of arguments"},
+	}
+	cc := new(SimpleChaincode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := cc.validateInput(tt.args)
+			wantError(t, err, tt.wantErr)
+			if tt.wantErr == "" && *state.AssetID != tt.wantID {
+				t.Fatalf("asset ID %q, expected %q", *state.AssetID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestEventPatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		event   string
+		want    map[string]interface{}
+		wantErr string
+	}{
+		{"readings", `{"assetID":"R1","maxTemperature":4}`, map[string]interface{}{"assetID": "R1", "maxTemperature": 4.0}, ""},
+		{"explicit null kept", `{"assetID":"R1","carrier":null}`, map[string]interface{}{"assetID": "R1", "carrier": nil}, ""},
+		{"computed dropped", `{"assetID":"R1","alerts":{},"compliant":true,"parent":"V1","version":3,"schemaVersion":"1.0","txnuuid":"x","txntimestamp":"y","noncompliantChildren":[]}`,
+			map[string]interface{}{"assetID": "R1"}, ""},
+		{"precondition dropped", `{"assetID":"R1","expectedVersion":3}`, map[string]interface{}{"assetID": "R1"}, ""},
+		{"asset type cleared", `{"assetID":"R1","assetType":null}`, nil, "The assetType of an asset cannot be cleared"},
+		{"not JSON", `{"assetID":`, nil, "Unable to unmarshal input JSON data"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := eventPatch(tt.event)
+			wantError(t, err, tt.wantErr)
+			if tt.wantErr == "" && !reflect.DeepEqual(patch, tt.want) {
+				t.Fatalf("patch %v, expected %v", patch, tt.want)
+			}
+		})
+	}
+}
+
+// TestMergePatch runs the examples of RFC 7386, appendix A
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		target string
+		patch  string
+		want   string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, tt := range tests {
+		var target, patch, want interface{}
+		json.Unmarshal([]byte(tt.target), &target)
+		json.Unmarshal([]byte(tt.patch), &patch)
+		json.Unmarshal([]byte(tt.want), &want)
+		if got := mergePatch(target, patch); !reflect.DeepEqual(got, want) {
+			gotJSON, _ := json.Marshal(got)
+			t.Errorf("merging %s into %s gave %s, expected %s", tt.patch, tt.target, gotJSON, tt.want)
+		}
+	}
+}
+
+// TestAlertRules sends the events of each case to a new asset, an hour
+// apart unless they carry device timestamps, and checks the alerts in force
+// after the last one
+func TestAlertRules(t *testing.T) {
+	tests := []struct {
+		name          string
+		assetType     string
+		events        []string
+		wantActive    []string
+		wantCompliant bool
+	}{
+		{"within limits", "ReeferContainer", []string{`"maxTemperature":4,"maxHumidity":60`}, nil, true},
+		{"overtemp", "ReeferContainer", []string{`"maxTemperature":61`}, []string{"OVERTEMP"}, false},
+		{"overtemp inclusive limit", "CrudeTank", []string{`"maxTemperature":60`}, nil, true},
+		{"overtemp in the tropics", "CrudeTank", []string{`"maxTemperature":46,"location":{"latitude":1.26,"longitude":103.84}`}, []string{"OVERTEMP"}, false},
+		{"overtemp in the tropics inclusive limit", "CrudeTank", []string{`"maxTemperature":45,"location":{"latitude":1.26,"longitude":103.84}`}, nil, true},
+		{"overtemp outside the tropics", "CrudeTank", []string{`"maxTemperature":46,"location":{"latitude":51.95,"longitude":4.15}`}, nil, true},
+		{"overhum", "ReeferContainer", []string{`"maxHumidity":81`}, []string{"overhum"}, false},
+		{"overhum inclusive limit", "ReeferContainer", []string{`"maxHumidity":80`}, nil, true},
+		{"overtemp clears", "CrudeTank", []string{
+			`"maxTemperature":70,"timestamp":"2017-03-20T00:00:00Z"`,
+			`"maxTemperature":55,"timestamp":"2017-03-21T00:00:00Z"`,
+		}, nil, true},
+		{"seal tamper", "CrudeTank", []string{`"sealBroken":true`}, []string{"SEALTAMPER"}, false},
+		{"seal tamper latched", "CrudeTank", []string{`"sealBroken":true`, `"sealBroken":false`}, []string{"SEALTAMPER"}, false},
+		{"temperature rate", "CrudeTank", []string{`"maxTemperature":20`, `"maxTemperature":26`}, []string{"TEMPRATE"}, false},
+		{"temperature rate falling", "CrudeTank", []string{`"maxTemperature":30`, `"maxTemperature":20`}, []string{"TEMPRATE"}, false},
+		{"temperature rate inclusive limit", "CrudeTank", []string{`"maxTemperature":20`, `"maxTemperature":25`}, nil, true},
+		{"temperature rate by device time", "CrudeTank", []string{
+			`"maxTemperature":20,"timestamp":"2017-03-20T00:00:00Z"`,
+			`"maxTemperature":40,"timestamp":"2017-03-20T10:00:00Z"`,
+		}, nil, true},
+		{"temperature rate kept without a reading", "CrudeTank", []string{
+			`"maxTemperature":20`, `"maxTemperature":30`, `"location":{"latitude":1,"longitude":2}`,
+		}, []string{"TEMPRATE"}, false},
+		{"temperature rate from the last temperature", "ReeferContainer", []string{
+			`"maxTemperature":20,"timestamp":"2017-03-20T00:00:00Z"`,
+			`"maxHumidity":50,"timestamp":"2017-03-20T09:00:00Z"`,
+			`"maxTemperature":40,"timestamp":"2017-03-20T10:00:00Z"`,
+		}, nil, true},
+		{"temperature rate from the last temperature by transaction time", "ReeferContainer", []string{
+			`"maxTemperature":20`, `"maxHumidity":50`, `"maxHumidity":52`, `"maxHumidity":54`, `"maxTemperature":30`,
+		}, nil, true},
+		{"humidity rate", "ReeferContainer", []string{`"maxHumidity":50`, `"maxHumidity":61`}, []string{"HUMRATE"}, false},
+		{"all at once", "ReeferContainer", []string{
+			`"maxTemperature":20,"maxHumidity":50`, `"maxTemperature":65,"maxHumidity":90,"sealBroken":true`,
+		}, []string{"HUMRATE", "OVERTEMP", "SEALTAMPER", "TEMPRATE", "overhum"}, false},
+		{"no rules for vessels", "Vessel", []string{`"location":{"latitude":1,"longitude":2}`}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestContract(t)
+			m.mustInvoke("createAsset", `{"assetID":"A1","assetType":"`+tt.assetType+`"}`)
+			for _, event := range tt.events {
+				m.mustInvoke("updateAsset", `{"assetID":"A1",`+event+`}`)
+			}
+			state := m.asset("A1")
+			if got := activeAlerts(state); !reflect.DeepEqual(got, tt.wantActive) {
+				t.Fatalf("active alerts %v, expected %v", got, tt.wantActive)
+			}
+			if *state.Compliance != tt.wantCompliant {
+				t.Fatalf("compliant %v, expected %v", *state.Compliance, tt.wantCompliant)
+			}
+		})
+	}
+}
+
+func TestReadingTimesOfClearedReadings(t *testing.T) {
+	m := newTestContract(t)
+	m.mustInvoke("createAsset", `{"assetID":"R1","assetType":"ReeferContainer","maxTemperature":20,"maxHumidity":50,"timestamp":"2017-03-20T00:00:00Z"}`)
+	m.mustInvoke("updateAsset", `{"assetID":"R1","maxTemperature":null,"timestamp":"2017-03-20T01:00:00Z"}`)
+	state := m.asset("R1")
+	if _, found := state.ReadingTimes["maxTemperature"]; found {
+		t.Fatalf("the cleared temperature keeps its reading time %v", state.ReadingTimes)
+	}
+	if _, found := state.ReadingTimes["maxHumidity"]; !found {
+		t.Fatalf("the humidity lost its reading time %v", state.ReadingTimes)
+	}
+	// a new temperature is a first reading, not a change from the cleared one
+	m.mustInvoke("updateAsset", `{"assetID":"R1","maxTemperature":60,"timestamp":"2017-03-20T02:00:00Z"}`)
+	state = m.asset("R1")
+	if got := activeAlerts(state); !reflect.DeepEqual(got, []string(nil)) {
+		t.Fatalf("active alerts %v, expected none", got)
+	}
+	if times := state.ReadingTimes["maxTemperature"]; times.Timestamp == nil || *times.Timestamp != "2017-03-20T02:00:00Z" {
+		t.Fatalf("the new temperature was not timed, reading times %v", state.ReadingTimes)
+	}
+	m.mustInvoke("updateAsset", `{"assetID":"R1","maxTemperature":null,"maxHumidity":null}`)
+	if state = m.asset("R1"); state.ReadingTimes != nil {
+		t.Fatalf("reading times %v are kept without readings", state.ReadingTimes)
+	}
+}
+
+func TestAlertTransitions(t *testing.T) {
+	m := newTestContract(t)
+	m.mustInvoke("createAsset", `{"assetID":"T1","assetType":"CrudeTank","maxTemperature":50,"timestamp":"2017-03-20T00:00:00Z"}`)
+	steps := []struct {
+		event       string
+		wantRaised  []string
+		wantCleared []string
+	}{
+		{`"maxTemperature":70,"timestamp":"2017-03-21T00:00:00Z"`, []string{"OVERTEMP"}, nil},
+		{`"maxTemperature":75,"timestamp":"2017-03-22T00:00:00Z"`, nil, nil},
+		{`"maxTemperature":50,"timestamp":"2017-03-23T00:00:00Z"`, nil, []string{"OVERTEMP"}},
+		{`"maxTemperature":50,"timestamp":"2017-03-24T00:00:00Z"`, nil, nil},
+	}
+	for i, step := range steps {
+		m.mustInvoke("updateAsset", `{"assetID":"T1",`+step.event+`}`)
+		state := m.asset("T1")
+		if got := alertNames(state.Alerts.Raised); !reflect.DeepEqual(got, step.wantRaised) {
+			t.Fatalf("event %d raised %v, expected %v", i, got, step.wantRaised)
+		}
+		if got := alertNames(state.Alerts.Cleared); !reflect.DeepEqual(got, step.wantCleared) {
+			t.Fatalf("event %d cleared %v, expected %v", i, got, step.wantCleared)
+		}
+	}
+}
+
+func TestExecuteRules(t *testing.T) {
+	var all, none, overtemp AlertArrayInternal
+	all = applicableAlerts(nil)
+	overtemp[AlertsOVERTEMP] = true
+	tests := []struct {
+		name             string
+		state            ArgsMap
+		applicable       AlertArrayInternal
+		suppressed       AlertArrayInternal
+		wantActive       []string
+		wantNoncompliant bool
+		wantErr          string
+	}{
+		{"compliant", ArgsMap{"maxTemperature": 20.0}, all, none, nil, false, ""},
+		{"overtemp", ArgsMap{"maxTemperature": 61.0}, all, none, []string{"OVERTEMP"}, true, ""},
+		{"not applicable", ArgsMap{"maxTemperature": 61.0}, none, none, nil, false, ""},
+		{"suppressed", ArgsMap{"maxTemperature": 61.0}, all, overtemp, nil, false, ""},
+		{"reading of the wrong type", ArgsMap{"maxTemperature": "hot"}, all, none, nil, false, ""},
+		{"test validation", ArgsMap{"testValidation": true}, all, none, nil, true, "testValidation property found and is true"},
+		{"test validation off", ArgsMap{"testValidation": false}, all, none, nil, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alerts := newAlertStatus()
+			ctx := ruleContext{event: &tt.state, applicable: tt.applicable, suppressed: tt.suppressed}
+			noncompliant, err := tt.state.executeRules(&ctx, &alerts)
+			wantError(t, err, tt.wantErr)
+			if noncompliant != tt.wantNoncompliant {
+				t.Fatalf("noncompliant %v, expected %v", noncompliant, tt.wantNoncompliant)
+			}
+			if got := alertNames(alerts.Active); tt.wantErr == "" && !reflect.DeepEqual(got, tt.wantActive) {
+				t.Fatalf("active alerts %v, expected %v", got, tt.wantActive)
+			}
+		})
+	}
+}
+
+func TestElapsedHours(t *testing.T) {
+	tests := []struct {
+		name   string
+		from   ArgsMap
+		to     ArgsMap
+		want   float64
+		wantOK bool
+	}{
+		{"device time", ArgsMap{"timestamp": "2017-03-20T00:00:00Z", "txntimestamp": "2017-03-20T00:00:00Z"},
+			ArgsMap{"timestamp": "2017-03-20T02:00:00Z", "txntimestamp": "2017-03-20T10:00:00Z"}, 2, true},
+		{"transaction time", ArgsMap{"txntimestamp": "2017-03-20T00:00:00Z"},
+			ArgsMap{"timestamp": "2017-03-20T02:00:00Z", "txntimestamp": "2017-03-20T00:30:00Z"}, 0.5, true},
+		{"clock went back", ArgsMap{"timestamp": "2017-03-20T02:00:00Z"}, ArgsMap{"timestamp": "2017-03-20T01:00:00Z"}, 0, false},
+		{"same time", ArgsMap{"timestamp": "2017-03-20T02:00:00Z"}, ArgsMap{"timestamp": "2017-03-20T02:00:00Z"}, 0, false},
+		{"no timestamps", ArgsMap{}, ArgsMap{"timestamp": "2017-03-20T02:00:00Z"}, 0, false},
+		{"not a timestamp", ArgsMap{"timestamp": "monday"}, ArgsMap{"timestamp": "tuesday"}, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := elapsedHours(tt.from, &tt.to)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("%s: elapsedHours returned %v, %v, expected %v, %v", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestGetObject(t *testing.T) {
+	state := ArgsMap{
+		"assetID":  "R1",
+		"location": map[string]interface{}{"latitude": 51.9, "longitude": 4.1},
+		"extension": map[string]interface{}{
+			"seal": map[string]interface{}{"id": "S1"},
+		},
+	}
+	tests := []struct {
+		qname     string
+		want      interface{}
+		wantFound bool
+	}{
+		{"assetID", "R1", true},
+		{"AssetID", "R1", true},
+		{"location.latitude", 51.9, true},
+		{"Location.Longitude", 4.1, true},
+		{"extension.seal.id", "S1", true},
+		{"location.altitude", nil, false},
+		{"assetID.length", nil, false},
+		{"carrier", nil, false},
+	}
+	for _, tt := range tests {
+		got, found := getObject(state, tt.qname)
+		if found != tt.wantFound || (found && !reflect.DeepEqual(got, tt.want)) {
+			t.Errorf("getObject %s returned %v, %v, expected %v, %v", tt.qname, got, found, tt.want, tt.wantFound)
+		}
+	}
+}
@@ -0,0 +1,414 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+// JSON Patch updates
+//
+// patchAsset applies an RFC 6902 patch to the stored JSON of an asset, for
+// the workflows that need more than a merge patch can say, such as appending
+// to an array or replacing a value only when a test on the stored state
+// holds. The patched state is turned back into an ordinary event, the merge
+// patch from the stored state to the patched one, so that it is validated
+// and runs the rules exactly as updateAsset would. Test operations may look
+// at every property, the others may not change what the contract calculates.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// PatchOperation is one operation of an RFC 6902 JSON patch
+type PatchOperation struct {
+	Op    string          `json:"op"`              // add, remove, replace, move, copy or test
+	Path  string          `json:"path"`            // JSON pointer to the location the operation targets
+	From  string          `json:"from,omitempty"`  // JSON pointer to the location moved or copied
+	Value json.RawMessage `json:"value,omitempty"` // value added, replaced or tested
+}
+
+// AssetPatch is a JSON patch to the stored state of an asset
+type AssetPatch struct {
+	AssetID string           `json:"assetID"`
+	Patch   []PatchOperation `json:"patch"`
+}
+
+//******************** patchAsset ********************/
+
+func (t *SimpleChaincode) patchAsset(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	var request AssetPatch
+	var stored map[string]interface{}
+	var patched interface{}
+
+	if len(args) != 1 {
+		return nil, errors.New("Incorrect number of arguments. Expecting a JSON strings with mandatory assetID")
+	}
+	err := json.Unmarshal([]byte(args[0]), &request)
+	if err != nil {
+		return nil, errors.New("Unable to unmarshal patch JSON data")
+	}
+	request.AssetID = strings.TrimSpace(request.AssetID)
+	if request.AssetID == "" {
+		return nil, errors.New("Asset id is mandatory in the input JSON data")
+	}
+	if len(request.Patch) == 0 {
+		return nil, errors.New("At least one patch operation is required")
+	}
+	state, err := t.getLiveAsset(stub, request.AssetID)
+	if err != nil {
+		return nil, err
+	}
+	// the stored state is decoded twice, once to patch and once to compare
+	stateJSON, err := json.Marshal(state)
+	if err != nil {
+		return nil, errors.New("Marshal failed for asset state" + fmt.Sprint(err))
+	}
+	err = json.Unmarshal(stateJSON, &stored)
+	if err == nil {
+		err = json.Unmarshal(stateJSON, &patched)
+	}
+	if err != nil {
+		return nil, errors.New("Unable to unmarshal state data obtained from ledger")
+	}
+	for i, op := range request.Patch {
+		patched, err = applyPatchOperation(patched, op)
+		if err != nil {
+			return nil, errors.New("Patch operation " + strconv.Itoa(i) + " (" + op.Op + " " + op.Path + ") failed: " + err.Error())
+		}
+	}
+	patchedMap, ok := patched.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("The patched state of asset " + request.AssetID + " is not an object")
+	}
+	event, err := patchEvent(stored, patchedMap, request.Patch)
+	if err != nil {
+		return nil, err
+	}
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return nil, errors.New("Marshal failed for patch event" + fmt.Sprint(err))
+	}
+	err = validateEvent(eventJSON)
+	if err != nil {
+		return nil, err
+	}
+	return t.createOrUpdateAsset(stub, []string{string(eventJSON)})
+}
+
+/*********************************  internal: patch event ****************************/
+
+// patchEvent turns the patched state into the event that updateAsset would
+// take: the merge patch from the stored state, plus the properties that the
+// operations wrote with their unchanged value, so that rules and the device
+// timestamp see them as carried by the event
+func patchEvent(stored map[string]interface{}, patched map[string]interface{}, ops []PatchOperation) (map[string]interface{}, error) {
+	if !reflect.DeepEqual(stored["assetID"], patched["assetID"]) {
+		return nil, errors.New("The assetID of an asset cannot be patched")
+	}
+	for _, name := range computedProperties {
+		if !reflect.DeepEqual(stored[name], patched[name]) {
+			return nil, errors.New("Property " + name + " is calculated by the contract and cannot be patched")
+		}
+		delete(stored, name)
+		delete(patched, name)
+	}
+	event := mergeDiff(stored, patched)
+	for _, op := range ops {
+		if op.Op == "test" || op.Op == "remove" {
+			continue
+		}
+		tokens, err := parsePointer(op.Path)
+		if err != nil || len(tokens) == 0 {
+			continue
+		}
+		if value, found := patched[tokens[0]]; found {
+			if _, changed := event[tokens[0]]; !changed {
+				event[tokens[0]] = value
+			}
+		}
+	}
+	event["assetID"] = patched["assetID"]
+	return event, nil
+}
+
+// mergeDiff returns the JSON merge patch that turns the original object
+// into the target
+func mergeDiff(original map[string]interface{}, target map[string]interface{}) map[string]interface{} {
+	diff := make(map[string]interface{})
+	for name := range original {
+		if _, found := target[name]; !found {
+			diff[name] = nil
+		}
+	}
+	for name, value := range target {
+		old, found := original[name]
+		if !found {
+			diff[name] = value
+			continue
+		}
+		oldMap, oldIsMap := old.(map[string]interface{})
+		newMap, newIsMap := value.(map[string]interface{})
+		if oldIsMap && newIsMap {
+			if nested := mergeDiff(oldMap, newMap); len(nested) > 0 {
+				diff[name] = nested
+			}
+			continue
+		}
+		if !reflect.DeepEqual(old, value) {
+			diff[name] = value
+		}
+	}
+	return diff
+}
+
+/*********************************  internal: JSON patch ****************************/
+
+// applyPatchOperation applies one RFC 6902 operation to a decoded JSON
+// document and returns the changed document
+func applyPatchOperation(doc interface{}, op PatchOperation) (interface{}, error) {
+	var value interface{}
+
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch op.Op {
+	case "add", "replace", "test":
+		if len(op.Value) == 0 {
+			return nil, errors.New("a value is required")
+		}
+		err = json.Unmarshal(op.Value, &value)
+		if err != nil {
+			return nil, errors.New("the value is not valid JSON: " + fmt.Sprint(err))
+		}
+	case "move", "copy":
+		if op.From == "" {
+			return nil, errors.New("a from pointer is required")
+		}
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err = pointerValue(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "copy" {
+			value = copyJSON(value)
+			break
+		}
+		if strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, errors.New("a location cannot be moved into one of its children")
+		}
+		doc, err = removeValue(doc, from)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch op.Op {
+	case "add", "move", "copy":
+		return addValue(doc, path, value)
+	case "remove":
+		return removeValue(doc, path)
+	case "replace":
+		if _, err = pointerValue(doc, path); err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return value, nil
+		}
+		doc, err = removeValue(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		return addValue(doc, path, value)
+	case "test":
+		current, err := pointerValue(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(current, value) {
+			currentJSON, _ := json.Marshal(current)
+			return nil, errors.New("test failed, the value is " + string(currentJSON))
+		}
+		return doc, nil
+	}
+	return nil, errors.New("unknown operation " + op.Op)
+}
+
+// parsePointer splits an RFC 6901 JSON pointer into its reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, errors.New("JSON pointer " + pointer + " does not start with /")
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+// pointerValue returns the value at the location
+func pointerValue(doc interface{}, tokens []string) (interface{}, error) {
+	for _, token := range tokens {
+		switch container := doc.(type) {
+		case map[string]interface{}:
+			value, found := container[token]
+			if !found {
+				return nil, errors.New("there is no member " + token)
+			}
+			doc = value
+		case []interface{}:
+			i, err := arrayIndex(container, token, false)
+			if err != nil {
+				return nil, err
+			}
+			doc = container[i]
+		default:
+			return nil, errors.New("cannot look up " + token + " in a " + jsonType(doc))
+		}
+	}
+	return doc, nil
+}
+
+// addValue adds a member to an object, inserts an element into an array or
+// replaces the whole document
+func addValue(doc interface{}, tokens []string, value interface{}) (interface{}, error) {
+	return changeParent(doc, tokens, value, func(parent interface{}, token string) (interface{}, error) {
+		switch container := parent.(type) {
+		case map[string]interface{}:
+			container[token] = value
+			return container, nil
+		case []interface{}:
+			i, err := arrayIndex(container, token, true)
+			if err != nil {
+				return nil, err
+			}
+			container = append(container, nil)
+			copy(container[i+1:], container[i:])
+			container[i] = value
+			return container, nil
+		}
+		return nil, errors.New("cannot add " + token + " to a " + jsonType(parent))
+	})
+}
+
+// removeValue removes a member of an object or an element of an array
+func removeValue(doc interface{}, tokens []string) (interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, errors.New("the whole document cannot be removed")
+	}
+	return changeParent(doc, tokens, nil, func(parent interface{}, token string) (interface{}, error) {
+		switch container := parent.(type) {
+		case map[string]interface{}:
+			if _, found := container[token]; !found {
+				return nil, errors.New("there is no member " + token)
+			}
+			delete(container, token)
+			return container, nil
+		case []interface{}:
+			i, err := arrayIndex(container, token, false)
+			if err != nil {
+				return nil, err
+			}
+			return append(container[:i], container[i+1:]...), nil
+		}
+		return nil, errors.New("cannot remove " + token + " from a " + jsonType(parent))
+	})
+}
+
+// changeParent walks to the container of the location, changes it and
+// stores the changed container back, as an array may be reallocated
+func changeParent(doc interface{}, tokens []string, root interface{}, change func(interface{}, string) (interface{}, error)) (interface{}, error) {
+	if len(tokens) == 0 {
+		return root, nil
+	}
+	if len(tokens) == 1 {
+		return change(doc, tokens[0])
+	}
+	child, err := pointerValue(doc, tokens[:1])
+	if err != nil {
+		return nil, err
+	}
+	child, err = changeParent(child, tokens[1:], root, change)
+	if err != nil {
+		return nil, err
+	}
+	switch container := doc.(type) {
+	case map[string]interface{}:
+		container[tokens[0]] = child
+	case []interface{}:
+		i, _ := arrayIndex(container, tokens[0], false)
+		container[i] = child
+	}
+	return doc, nil
+}
+
+// arrayIndex parses an array index, "-" and the length are accepted only
+// where an element may be added. RFC 6901 writes an index as 0 or as
+// digits without a leading zero, no sign.
+func arrayIndex(array []interface{}, token string, adding bool) (int, error) {
+	if adding && token == "-" {
+		return len(array), nil
+	}
+	if !isArrayIndex(token) {
+		return 0, errors.New(token + " is not an array index")
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil {
+		return 0, errors.New(token + " is not an array index")
+	}
+	if i > len(array) || (i == len(array) && !adding) {
+		return 0, errors.New("index " + token + " is out of bounds")
+	}
+	return i, nil
+}
+
+// isArrayIndex returns true for 0 and for digits that do not start with 0
+func isArrayIndex(token string) bool {
+	if token == "" || (token[0] == '0' && token != "0") {
+		return false
+	}
+	for _, c := range token {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// copyJSON copies a decoded JSON value so that a copy does not share
+// objects or arrays with its source
+func copyJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for name, member := range v {
+			c[name] = copyJSON(member)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, element := range v {
+			c[i] = copyJSON(element)
+		}
+		return c
+	}
+	return value
+}
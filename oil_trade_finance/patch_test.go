@@ -0,0 +1,107 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestPatchAsset(t *testing.T) {
+	steps := []struct {
+		name    string
+		patch   string
+		wantErr string
+		check   func(state AssetState) bool
+	}{
+		{"append to an array", `[{"op":"add","path":"/extension/ports/-","value":"Singapore"}]`, "",
+			func(s AssetState) bool {
+				return reflect.DeepEqual(s.Extension["ports"], []interface{}{"Rotterdam", "Singapore"})
+			}},
+		{"replace when the test holds", `[{"op":"test","path":"/carrier","value":"alpha"},{"op":"replace","path":"/maxTemperature","value":70}]`, "",
+			func(s AssetState) bool { return *s.MaxTemperature == 70 && activeAlerts(s) != nil }},
+		{"replace when the test fails", `[{"op":"test","path":"/carrier","value":"beta"},{"op":"replace","path":"/maxTemperature","value":10}]`,
+			`Patch operation 0 (test /carrier) failed: test failed, the value is "alpha"`,
+			func(s AssetState) bool { return *s.MaxTemperature == 70 }},
+		{"remove", `[{"op":"remove","path":"/extension/ports/0"}]`, "",
+			func(s AssetState) bool { return reflect.DeepEqual(s.Extension["ports"], []interface{}{"Singapore"}) }},
+		{"move", `[{"op":"move","from":"/extension/ports","path":"/extension/calls"}]`, "",
+			func(s AssetState) bool { return s.Extension["ports"] == nil && s.Extension["calls"] != nil }},
+		{"copy", `[{"op":"copy","from":"/location/latitude","path":"/location/longitude"}]`, "",
+			func(s AssetState) bool { return *s.Location.Longitude == 51.9 }},
+		{"invalid result", `[{"op":"replace","path":"/maxTemperature","value":"hot"}]`, "Invalid event: maxTemperature: expecting [number null], got string",
+			func(s AssetState) bool { return *s.MaxTemperature == 70 }},
+		{"asset ID", `[{"op":"replace","path":"/assetID","value":"T2"}]`, "The assetID of an asset cannot be patched", nil},
+		{"calculated property", `[{"op":"replace","path":"/compliant","value":true}]`, "Property compliant is calculated by the contract and cannot be patched", nil},
+		{"missing property", `[{"op":"replace","path":"/humidity","value":1}]`, "Patch operation 0 (replace /humidity) failed", nil},
+		{"no operation", `[]`, "args[0].patch: expecting at least 1 item(s), got 0", nil},
+	}
+
+	m := newCustodyContract(t)
+	m.mustInvoke("updateAsset", `{"assetID":"T1","location":{"latitude":51.9,"longitude":4.1},"extension":{"ports":["Rotterdam"]}}`)
+	for _, step := range steps {
+		version := *m.asset("T1").Version
+		_, err := m.invoke("patchAsset", `{"assetID":"T1","patch":`+step.patch+`}`)
+		wantError(t, err, step.wantErr)
+		state := m.asset("T1")
+		if step.check != nil && !step.check(state) {
+			t.Fatalf("%s: unexpected state %+v", step.name, state)
+		}
+		wantVersion := version
+		if step.wantErr == "" {
+			wantVersion++
+		}
+		if *state.Version != wantVersion {
+			t.Fatalf("%s: version %d, expected %d", step.name, *state.Version, wantVersion)
+		}
+	}
+}
+
+func TestApplyPatchOperation(t *testing.T) {
+	tests := []struct {
+		doc     string
+		op      string
+		want    string
+		wantErr string
+	}{
+		{`{"a":1}`, `{"op":"add","path":"/b","value":2}`, `{"a":1,"b":2}`, ""},
+		{`{"a":[1,3]}`, `{"op":"add","path":"/a/1","value":2}`, `{"a":[1,2,3]}`, ""},
+		{`{"a":1}`, `{"op":"add","path":"","value":[1]}`, `[1]`, ""},
+		{`{"a~b":1}`, `{"op":"remove","path":"/a~0b"}`, `{}`, ""},
+		{`{"a":{"b":1}}`, `{"op":"move","from":"/a","path":"/a/c"}`, ``, "a location cannot be moved into one of its children"},
+		{`{"a":1}`, `{"op":"replace","path":"/b","value":2}`, ``, "there is no member b"},
+		{`{"a":1}`, `{"op":"add","path":"/b"}`, ``, "a value is required"},
+		{`{"a":1}`, `{"op":"copy","path":"/b"}`, ``, "a from pointer is required"},
+		{`{"a":1}`, `{"op":"add","path":"b","value":2}`, ``, "JSON pointer b does not start with /"},
+		{`{"a":1}`, `{"op":"merge","path":"/a"}`, ``, "unknown operation merge"},
+		{`{"arr":[1,2]}`, `{"op":"replace","path":"/arr/+1","value":3}`, ``, "+1 is not an array index"},
+		{`{"arr":[1,2]}`, `{"op":"replace","path":"/arr/01","value":3}`, ``, "01 is not an array index"},
+		{`{"arr":[1,2]}`, `{"op":"remove","path":"/arr/-0"}`, ``, "-0 is not an array index"},
+		{`{"arr":[1,2]}`, `{"op":"replace","path":"/arr/-","value":3}`, ``, "- is not an array index"},
+		{`{"arr":[1,2]}`, `{"op":"replace","path":"/arr/0","value":3}`, `{"arr":[3,2]}`, ""},
+	}
+	for _, tt := range tests {
+		var doc, want interface{}
+		var op PatchOperation
+		json.Unmarshal([]byte(tt.doc), &doc)
+		json.Unmarshal([]byte(tt.op), &op)
+		got, err := applyPatchOperation(doc, op)
+		wantError(t, err, tt.wantErr)
+		if err != nil {
+			continue
+		}
+		json.Unmarshal([]byte(tt.want), &want)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s on %s gave %v, expected %s", tt.op, tt.doc, got, tt.want)
+		}
+	}
+}
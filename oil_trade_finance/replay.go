@@ -0,0 +1,349 @@
+//go:build simulator
+// +build simulator
+
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+// Event replay
+//
+// The replay command of the simulator judges past shipments by the rules of
+// this contract version. It replays an exported event log through the
+// contract against an empty in-memory ledger and reports the compliance and
+// active alerts of every asset at the end, compared with the states that
+// were recorded on the ledger when one is given:
+//     go run -tags simulator . replay [-recorded FILE] [-states FILE] LOG
+//
+// The log is a JSON array of ReplayEntry, or one entry per line. Entries run
+// in the order of the log, each with its own timestamp, transaction ID and
+// caller, so that a log always replays to the same states. Entries without a
+// transaction ID are numbered in order, failed ones included. The contract is
+// initialized for this version before the first entry, the init entries of
+// the log are skipped as the version they name may be an older one. A
+// transaction that fails is reported and the replay goes on.
+//
+// The recorded states are either a ledger file of the simulator or a JSON
+// array of asset states as readAsset returns them. -states writes the final
+// world state of the replay as a ledger file, which the simulator can carry
+// on from and a later replay can take as recorded states.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// ReplayEntry is one transaction of an exported event log
+type ReplayEntry struct {
+	Function  string            `json:"function"`         // init or the function invoked
+	Args      []json.RawMessage `json:"args"`             // a JSON string is passed as it is, any other value as its JSON text
+	Timestamp string            `json:"timestamp"`        // RFC3339 transaction timestamp
+	TxID      string            `json:"txID,omitempty"`   // transaction ID, numbered in order when absent
+	Caller    string            `json:"caller,omitempty"` // common name of the submitter, admin when absent
+	Roles     *string           `json:"roles,omitempty"`  // role attribute of the submitter, admin when absent
+}
+
+// replayOutcome is the compliance of an asset at the end of a replay or on
+// the recorded ledger
+type replayOutcome struct {
+	Compliant bool
+	Active    []string
+	Deleted   bool
+}
+
+func init() {
+	simulatorTools["replay"] = replay
+}
+
+// replay runs the replay command and returns the exit status
+func replay(cc shim.Chaincode, arguments []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	recordedFile := flags.String("recorded", "", "recorded ledger file or JSON array of asset states to compare with")
+	statesFile := flags.String("states", "", "ledger file to write the final world state of the replay to")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: simulator replay [-recorded FILE] [-states FILE] LOG")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(arguments); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	err := replayLog(cc, flags.Arg(0), *recordedFile, *statesFile, stdout)
+	if err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return 1
+	}
+	return 0
+}
+
+// replayLog replays the log, writes the final states and prints the report
+func replayLog(cc shim.Chaincode, logFile string, recordedFile string, statesFile string, out io.Writer) error {
+	var recorded map[string]replayOutcome
+
+	entries, err := readReplayLog(logFile)
+	if err != nil {
+		return err
+	}
+	if recordedFile != "" {
+		recorded, err = readRecordedOutcomes(recordedFile)
+		if err != nil {
+			return err
+		}
+	}
+	ledger, err := replayEntries(cc, entries, out)
+	if err != nil {
+		return err
+	}
+	if statesFile != "" {
+		err = ledger.save(statesFile)
+		if err != nil {
+			return err
+		}
+	}
+	replayed, err := ledgerOutcomes(ledger)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, "final states:")
+	for _, id := range outcomeIDs(replayed, nil) {
+		fmt.Fprintln(out, " ", id, replayed[id])
+	}
+	if recorded == nil {
+		return nil
+	}
+	fmt.Fprintln(out, "compared with the recorded states:")
+	unchanged := 0
+	for _, id := range outcomeIDs(replayed, recorded) {
+		now, replayedFound := replayed[id]
+		then, recordedFound := recorded[id]
+		switch {
+		case !recordedFound:
+			fmt.Fprintln(out, "+", id, now, "(not recorded)")
+		case !replayedFound:
+			fmt.Fprintln(out, "-", id, then, "(not replayed)")
+		case now.String() == then.String():
+			unchanged++
+		default:
+			fmt.Fprintln(out, "~", id, then, "->", now)
+		}
+	}
+	fmt.Fprintln(out, unchanged, "asset(s) judged as recorded")
+	return nil
+}
+
+// replayEntries replays the log against an empty ledger initialized for
+// this contract version
+func replayEntries(cc shim.Chaincode, entries []ReplayEntry, out io.Writer) (*simulatorLedger, error) {
+	ledger := &simulatorLedger{State: make(map[string]json.RawMessage)}
+	if len(entries) == 0 {
+		return nil, errors.New("The log holds no entries")
+	}
+
+	// initialized a second before the first entry, as the first entry of
+	// the log, be it an init or not, must find the contract ready
+	first, err := time.Parse(time.RFC3339Nano, entries[0].Timestamp)
+	if err != nil {
+		return nil, errors.New("Entry 0: the timestamp is not an RFC3339 timestamp: " + entries[0].Timestamp)
+	}
+	initArgs := []string{"init", `{"version":"` + MYVERSION + `"}`, `{"tradeID":"` + TRADEID + `"}`}
+	stub, err := newSimulatorStub(ledger, initArgs, ADMINROLE, ADMINROLE, first.Add(-time.Second))
+	if err != nil {
+		return nil, err
+	}
+	resp := stub.execute(cc, true)
+	if resp.Status != shim.OK {
+		return nil, errors.New("Unable to initialize the contract: " + resp.Message)
+	}
+	stub.commit()
+
+	applied, skipped, failed := 0, 0, 0
+	for i, entry := range entries {
+		if entry.Function == "init" {
+			skipped++
+			continue
+		}
+		if entry.Function == "" {
+			return nil, fmt.Errorf("Entry %d: the function is mandatory", i)
+		}
+		txTime, err := time.Parse(time.RFC3339Nano, entry.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("Entry %d: the timestamp is not an RFC3339 timestamp: %s", i, entry.Timestamp)
+		}
+		args := []string{entry.Function}
+		for _, arg := range entry.Args {
+			var s string
+			if json.Unmarshal(arg, &s) != nil {
+				s = string(arg)
+			}
+			args = append(args, s)
+		}
+		caller, roles := ADMINROLE, ADMINROLE
+		if entry.Caller != "" {
+			caller = entry.Caller
+		}
+		if entry.Roles != nil {
+			roles = *entry.Roles
+		}
+		stub, err := newSimulatorStub(ledger, args, caller, roles, txTime)
+		if err != nil {
+			return nil, fmt.Errorf("Entry %d: %v", i, err)
+		}
+		if entry.TxID != "" {
+			stub.txID = entry.TxID
+		}
+		resp := stub.execute(cc, false)
+		if resp.Status != shim.OK {
+			fmt.Fprintf(out, "entry %d %s %s failed: %s\n", i, entry.Function, stub.txID, resp.Message)
+			// the failed transaction keeps its ID, as one recorded invalid on
+			// a peer does, the next one gets a new one
+			ledger.TxCount++
+			failed++
+			continue
+		}
+		stub.commit()
+		applied++
+	}
+	fmt.Fprintf(out, "replayed %d entries: %d applied, %d failed, %d init skipped\n", len(entries), applied, failed, skipped)
+	return ledger, nil
+}
+
+// readReplayLog reads a JSON array of entries or one entry per line
+func readReplayLog(logFile string) ([]ReplayEntry, error) {
+	var entries []ReplayEntry
+
+	logBytes, err := os.ReadFile(logFile)
+	if err != nil {
+		return nil, errors.New("Unable to read log: " + fmt.Sprint(err))
+	}
+	if trimmed := bytes.TrimSpace(logBytes); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &entries)
+		if err != nil {
+			return nil, errors.New("Unable to unmarshal log: " + fmt.Sprint(err))
+		}
+		return entries, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(logBytes))
+	for decoder.More() {
+		var entry ReplayEntry
+		err = decoder.Decode(&entry)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to unmarshal entry %d of the log: %v", len(entries), err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// readRecordedOutcomes reads the recorded states, a ledger file or an array
+// of asset states
+func readRecordedOutcomes(recordedFile string) (map[string]replayOutcome, error) {
+	var states []json.RawMessage
+	var ledger simulatorLedger
+
+	recordedBytes, err := os.ReadFile(recordedFile)
+	if err != nil {
+		return nil, errors.New("Unable to read recorded states: " + fmt.Sprint(err))
+	}
+	if trimmed := bytes.TrimSpace(recordedBytes); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &states)
+		if err != nil {
+			return nil, errors.New("Unable to unmarshal recorded states: " + fmt.Sprint(err))
+		}
+		ledger.State = make(map[string]json.RawMessage)
+		for i, state := range states {
+			var asset AssetState
+			err = json.Unmarshal(state, &asset)
+			if err != nil || asset.AssetID == nil {
+				return nil, fmt.Errorf("Recorded state %d is not an asset state", i)
+			}
+			ledger.State[*asset.AssetID] = state
+		}
+	} else {
+		err = json.Unmarshal(recordedBytes, &ledger)
+		if err != nil {
+			return nil, errors.New("Unable to unmarshal recorded ledger: " + fmt.Sprint(err))
+		}
+	}
+	return ledgerOutcomes(&ledger)
+}
+
+// ledgerOutcomes returns the outcome of every asset on the ledger, the
+// states of older contract versions migrated as the contract reads them
+func ledgerOutcomes(ledger *simulatorLedger) (map[string]replayOutcome, error) {
+	outcomes := make(map[string]replayOutcome)
+	for key, value := range ledger.State {
+		var state AssetState
+
+		if !isAssetStateKey(key) {
+			continue
+		}
+		assetBytes, _, err := migrateAssetBytes(value)
+		if err != nil {
+			return nil, errors.New("Unable to migrate asset " + key + ": " + fmt.Sprint(err))
+		}
+		err = json.Unmarshal(assetBytes, &state)
+		if err != nil {
+			return nil, errors.New("Unable to unmarshal asset " + key + ": " + fmt.Sprint(err))
+		}
+		var outcome replayOutcome
+		if state.Compliance != nil {
+			outcome.Compliant = *state.Compliance
+		}
+		if state.Alerts != nil {
+			outcome.Active = append([]string{}, state.Alerts.Active...)
+			sort.Strings(outcome.Active)
+		}
+		_, outcome.Deleted = ledger.State[TOMBSTONEKEYPREFIX+key]
+		outcomes[key] = outcome
+	}
+	return outcomes, nil
+}
+
+// outcomeIDs returns the asset IDs of both sets of outcomes in order
+func outcomeIDs(a map[string]replayOutcome, b map[string]replayOutcome) []string {
+	ids := make([]string, 0, len(a)+len(b))
+	for id := range a {
+		ids = append(ids, id)
+	}
+	for id := range b {
+		if _, found := a[id]; !found {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (o replayOutcome) String() string {
+	s := "noncompliant"
+	if o.Compliant {
+		s = "compliant"
+	}
+	s += " [" + strings.Join(o.Active, " ") + "]"
+	if o.Deleted {
+		s += " deleted"
+	}
+	return s
+}
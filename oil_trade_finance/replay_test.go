@@ -0,0 +1,139 @@
+//go:build simulator
+// +build simulator
+
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// replayTestLog starts with the init of an older version, which the replay
+// skips, and has an entry that the access policy refuses
+const replayTestLog = `{"function":"init","args":[{"version":"1.0"},{"tradeID":"0476219"}],"timestamp":"2017-03-20T07:00:00Z"}
+{"function":"createAsset","args":[{"assetID":"T1","assetType":"CrudeTank","maxTemperature":20}],"timestamp":"2017-03-20T08:00:00Z","txID":"a1"}
+{"function":"updateAsset","args":["{\"assetID\":\"T1\",\"maxTemperature\":80}"],"timestamp":"2017-03-20T09:00:00Z"}
+{"function":"createAsset","args":[{"assetID":"T2","assetType":"CrudeTank"}],"timestamp":"2017-03-20T10:00:00Z","caller":"dev","roles":"device"}
+{"function":"createAsset","args":[{"assetID":"T3","assetType":"CrudeTank"}],"timestamp":"2017-03-20T10:00:00Z"}
+`
+
+func TestReplay(t *testing.T) {
+	tests := []struct {
+		name       string
+		log        string
+		recorded   string
+		wantStatus int
+		wantOut    []string
+		wantErr    string
+	}{
+		{"no recorded states", replayTestLog, "", 0, []string{
+			"entry 3 createAsset tx000004 failed: Permission denied: createAsset",
+			"replayed 5 entries: 3 applied, 1 failed, 1 init skipped",
+			"  T1 noncompliant [OVERTEMP TEMPRATE]\n  T3 compliant []\n",
+		}, ""},
+		{"recorded asset states", replayTestLog,
+			`[{"assetID":"T1","compliant":true,"alerts":{"active":[]}},{"assetID":"T3","compliant":true},{"assetID":"T9","compliant":true}]`, 0, []string{
+				"~ T1 compliant [] -> noncompliant [OVERTEMP TEMPRATE]",
+				"- T9 compliant [] (not replayed)",
+				"1 asset(s) judged as recorded",
+			}, ""},
+		{"recorded 1.0 ledger", replayTestLog,
+			`{"state":{"T1":{"assetID":"T1","temperature":80,"alerts":"OVERTEMP"},"ContractStateKey":{"version":"1.0"}}}`, 0, []string{
+				"~ T1 noncompliant [] -> noncompliant [OVERTEMP TEMPRATE]",
+				"+ T3 compliant [] (not recorded)",
+			}, ""},
+		{"JSON array log", `[{"function":"createAsset","args":[{"assetID":"T1","assetType":"Vessel"}],"timestamp":"2017-03-20T08:00:00Z"}]`, "", 0,
+			[]string{"replayed 1 entries: 1 applied, 0 failed, 0 init skipped"}, ""},
+		{"bad timestamp", `{"function":"createAsset","args":[],"timestamp":"today"}`, "", 1, nil,
+			"error: Entry 0: the timestamp is not an RFC3339 timestamp: today"},
+		{"no function", `{"args":[],"timestamp":"2017-03-20T08:00:00Z"}`, "", 1, nil, "error: Entry 0: the function is mandatory"},
+		{"empty log", ``, "", 1, nil, "error: The log holds no entries"},
+		{"recorded state without ID", replayTestLog, `[{"compliant":true}]`, 1, nil, "error: Recorded state 0 is not an asset state"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+
+			dir := t.TempDir()
+			logFile := filepath.Join(dir, "log.json")
+			os.WriteFile(logFile, []byte(tt.log), 0644)
+			args := []string{"replay"}
+			if tt.recorded != "" {
+				os.WriteFile(filepath.Join(dir, "recorded.json"), []byte(tt.recorded), 0644)
+				args = append(args, "-recorded", filepath.Join(dir, "recorded.json"))
+			}
+			status := simulate(new(SimpleChaincode), append(args, logFile), &stdout, &stderr)
+			if status != tt.wantStatus {
+				t.Fatalf("status %d, expected %d, stderr %s", status, tt.wantStatus, stderr.String())
+			}
+			for _, want := range tt.wantOut {
+				if !strings.Contains(stdout.String(), want) {
+					t.Fatalf("output does not contain %q:\n%s", want, stdout.String())
+				}
+			}
+			if !strings.Contains(stderr.String(), tt.wantErr) {
+				t.Fatalf("stderr does not contain %q:\n%s", tt.wantErr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestReplayIsDeterministic(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "log.json")
+	os.WriteFile(logFile, []byte(replayTestLog), 0644)
+
+	var states [2][]byte
+	for i := range states {
+		var stdout, stderr bytes.Buffer
+		statesFile := filepath.Join(dir, "states.json")
+		if status := simulate(new(SimpleChaincode), []string{"replay", "-states", statesFile, logFile}, &stdout, &stderr); status != 0 {
+			t.Fatalf("replay %d failed: %s", i, stderr.String())
+		}
+		states[i], _ = os.ReadFile(statesFile)
+	}
+	if len(states[0]) == 0 || !bytes.Equal(states[0], states[1]) {
+		t.Fatalf("two replays of the log gave different states:\n%s\n%s", states[0], states[1])
+	}
+	// the final states compare as judged by the same rules
+	var stdout, stderr bytes.Buffer
+	simulate(new(SimpleChaincode), []string{"replay", "-recorded", filepath.Join(dir, "states.json"), logFile}, &stdout, &stderr)
+	if !strings.Contains(stdout.String(), "2 asset(s) judged as recorded") {
+		t.Fatalf("the replay does not match its own states:\n%s", stdout.String())
+	}
+}
+
+// the entry after a failed one gets a transaction ID of its own
+func TestReplayNumbersFailedEntries(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	var ledger simulatorLedger
+	var state AssetState
+
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "log.json")
+	statesFile := filepath.Join(dir, "states.json")
+	os.WriteFile(logFile, []byte(replayTestLog), 0644)
+	if status := simulate(new(SimpleChaincode), []string{"replay", "-states", statesFile, logFile}, &stdout, &stderr); status != 0 {
+		t.Fatalf("replay failed: %s", stderr.String())
+	}
+	ledgerBytes, _ := os.ReadFile(statesFile)
+	json.Unmarshal(ledgerBytes, &ledger)
+	json.Unmarshal(ledger.State["T3"], &state)
+	if *state.TxnID != "tx000005" || ledger.TxCount != 5 {
+		t.Fatalf("T3 written by %s after %d transactions, expected tx000005 after 5", *state.TxnID, ledger.TxCount)
+	}
+}
@@ -0,0 +1,701 @@
+//go:build ignore
+// +build ignore
+
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+// Schema and sample generator
+//
+// generate_go_schema writes schemas.go and samples.go for the contract in the
+// current directory. Every property of every schema is derived from the Go
+// type that the contract marshals, so a field that is added, renamed or
+// retyped in the code changes the published API with it. scripts/api.json
+// only adds what the types cannot say: the functions and their arguments,
+// descriptions, enums, formats and which properties are required.
+//
+// Run it from the contract directory with
+//     go generate
+//
+// A schema spec in api.json is a JSON Schema fragment with these additions:
+//     "go":       a Go type expression, e.g. "AssetState" or "[]Tombstone"
+//     "ref":      the name of one of the objectModelSchemas
+//     "only":     the properties to keep from the derived schema
+//     "omit":     the properties to drop from the derived schema
+//     "enumFrom": a variable whose literal lists the allowed values, either
+//                 a map of strings, e.g. "AlertsName", or a slice of structs
+//                 and the field to take, e.g. "assetTypes.Name"
+//     "nullable": true to let every property, also those of nested objects,
+//                 be null, as a merge patch clears a property with null
+// Every other key is merged into the derived schema, recursively for
+// properties and items.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// apiDescriptionFile is read relative to the contract directory
+const apiDescriptionFile = "scripts/api.json"
+
+// sampleTimestamp is the value of every sample date-time
+const sampleTimestamp = "2017-03-31T19:25:26.66251366+02:00"
+
+type schema map[string]interface{}
+
+// apiDescription is the content of api.json
+type apiDescription struct {
+	Types              map[string]schema      `json:"types"`              // overrides applied wherever a Go type is used
+	ObjectModelSchemas map[string]schema      `json:"objectModelSchemas"` // named schemas, published and available to "ref"
+	API                map[string]apiFunction `json:"API"`
+	Samples            map[string]sampleSpec  `json:"samples"`
+}
+
+// apiFunction describes one function of the contract API
+type apiFunction struct {
+	Method      string `json:"method"` // deploy, invoke or query
+	Description string `json:"description"`
+	Args        schema `json:"args"`   // the one argument, absent when the function takes none
+	Result      schema `json:"result"` // absent when the function returns nothing
+}
+
+// sampleSpec describes one sample object
+type sampleSpec struct {
+	Schema schema                 `json:"schema"`
+	Values map[string]interface{} `json:"values"` // replace the values generated from the schema
+}
+
+type generator struct {
+	api    apiDescription
+	types  map[string]ast.Expr          // type declarations of the contract
+	values map[string]*ast.CompositeLit // variables initialized with a composite literal
+	models map[string]schema            // objectModelSchemas resolved so far
+	active map[string]bool              // types and models being resolved, to stop recursion
+}
+
+func main() {
+	g := &generator{
+		types:  make(map[string]ast.Expr),
+		values: make(map[string]*ast.CompositeLit),
+		models: make(map[string]schema),
+		active: make(map[string]bool),
+	}
+	err := g.parsePackage(".")
+	if err != nil {
+		log.Fatal(err)
+	}
+	apiJSON, err := ioutil.ReadFile(apiDescriptionFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = json.Unmarshal(apiJSON, &g.api)
+	if err != nil {
+		log.Fatal(apiDescriptionFile + ": " + err.Error())
+	}
+
+	schemas, err := g.schemas()
+	if err != nil {
+		log.Fatal(err)
+	}
+	samples, err := g.samples()
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = writeGoString("schemas.go", "schemas", schemas)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = writeGoString("samples.go", "samples", samples)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// parsePackage collects the type declarations and literal variables of the
+// contract sources, leaving out tests and the generated files
+func (g *generator) parsePackage(dir string) error {
+	fset := token.NewFileSet()
+	sources := func(fi os.FileInfo) bool {
+		name := fi.Name()
+		return !strings.HasSuffix(name, "_test.go") && name != "schemas.go" && name != "samples.go"
+	}
+	pkgs, err := parser.ParseDir(fset, dir, sources, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				gen, ok := decl.(*ast.GenDecl)
+				if !ok {
+					continue
+				}
+				for _, spec := range gen.Specs {
+					switch s := spec.(type) {
+					case *ast.TypeSpec:
+						g.types[s.Name.Name] = s.Type
+					case *ast.ValueSpec:
+						for i, name := range s.Names {
+							if i < len(s.Values) {
+								if lit, ok := s.Values[i].(*ast.CompositeLit); ok {
+									g.values[name.Name] = lit
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+/*********************************  schemas ****************************/
+
+func (g *generator) schemas() (schema, error) {
+	models := make(schema)
+	for _, name := range sortedKeys(g.api.ObjectModelSchemas) {
+		model, err := g.model(name)
+		if err != nil {
+			return nil, err
+		}
+		models[name] = model
+	}
+	api := make(schema)
+	for name, f := range g.api.API {
+		function, err := g.function(name, f)
+		if err != nil {
+			return nil, fmt.Errorf("API %s: %v", name, err)
+		}
+		api[name] = function
+	}
+	return schema{"API": api, "objectModelSchemas": models}, nil
+}
+
+// model resolves one of the objectModelSchemas
+func (g *generator) model(name string) (schema, error) {
+	if model, found := g.models[name]; found {
+		return model, nil
+	}
+	spec, found := g.api.ObjectModelSchemas[name]
+	if !found {
+		return nil, errors.New("unknown object model schema " + name)
+	}
+	if g.active["ref:"+name] {
+		return nil, errors.New("object model schema " + name + " refers to itself")
+	}
+	g.active["ref:"+name] = true
+	model, err := g.resolve(spec)
+	delete(g.active, "ref:"+name)
+	if err != nil {
+		return nil, fmt.Errorf("object model schema %s: %v", name, err)
+	}
+	g.models[name] = model
+	return model, nil
+}
+
+// function lays out the schema of an API function
+func (g *generator) function(name string, f apiFunction) (schema, error) {
+	args := schema{
+		"description": "accepts no arguments",
+		"items":       schema{},
+		"maxItems":    0,
+		"minItems":    0,
+		"type":        "array",
+	}
+	if f.Args != nil {
+		items, err := g.resolve(f.Args)
+		if err != nil {
+			return nil, err
+		}
+		args = schema{
+			"description": "args are JSON encoded strings",
+			"items":       items,
+			"maxItems":    1,
+			"minItems":    1,
+			"type":        "array",
+		}
+	}
+	properties := schema{
+		"args": args,
+		"function": schema{
+			"description": name + " function",
+			"enum":        []interface{}{name},
+			"type":        "string",
+		},
+		"method": f.Method,
+	}
+	if f.Result != nil {
+		result, err := g.resolve(f.Result)
+		if err != nil {
+			return nil, err
+		}
+		properties["result"] = result
+	}
+	return schema{
+		"description": f.Description,
+		"properties":  properties,
+		"type":        "object",
+	}, nil
+}
+
+// resolve turns a spec from api.json into a schema
+func (g *generator) resolve(spec schema) (schema, error) {
+	base := make(schema)
+	if goType, found := spec["go"].(string); found {
+		expr, err := parser.ParseExpr(goType)
+		if err != nil {
+			return nil, errors.New("bad Go type " + goType + ": " + err.Error())
+		}
+		base, err = g.goSchema(expr)
+		if err != nil {
+			return nil, err
+		}
+	} else if ref, found := spec["ref"].(string); found {
+		model, err := g.model(ref)
+		if err != nil {
+			return nil, err
+		}
+		base = deepCopy(model).(schema)
+	}
+	return g.apply(base, spec)
+}
+
+// apply merges a spec into a schema
+func (g *generator) apply(base schema, spec schema) (schema, error) {
+	if only, found := spec["only"].([]interface{}); found {
+		keep := make(map[string]bool)
+		for _, name := range only {
+			keep[fmt.Sprint(name)] = true
+		}
+		filterProperties(base, func(name string) bool { return keep[name] })
+	}
+	if omit, found := spec["omit"].([]interface{}); found {
+		drop := make(map[string]bool)
+		for _, name := range omit {
+			drop[fmt.Sprint(name)] = true
+		}
+		filterProperties(base, func(name string) bool { return !drop[name] })
+	}
+	if nullable, _ := spec["nullable"].(bool); nullable {
+		makeNullable(base)
+	}
+	for _, key := range sortedKeys(spec) {
+		value := spec[key]
+		switch key {
+		case "go", "ref", "only", "omit", "enumFrom", "nullable":
+		case "properties":
+			properties, _ := asSchema(base["properties"])
+			if properties == nil {
+				properties = make(schema)
+			}
+			overrides, ok := asSchema(value)
+			if !ok {
+				return nil, errors.New("properties must be an object")
+			}
+			for _, name := range sortedKeys(overrides) {
+				merged, err := g.merge(properties[name], overrides[name])
+				if err != nil {
+					return nil, fmt.Errorf("%s: %v", name, err)
+				}
+				properties[name] = merged
+			}
+			base["properties"] = properties
+		case "items":
+			merged, err := g.merge(base["items"], value)
+			if err != nil {
+				return nil, fmt.Errorf("items: %v", err)
+			}
+			base["items"] = merged
+		default:
+			base[key] = deepCopy(value)
+		}
+	}
+	if from, found := spec["enumFrom"].(string); found {
+		enum, err := g.enumValues(from)
+		if err != nil {
+			return nil, err
+		}
+		if base["type"] == "array" {
+			items, _ := asSchema(base["items"])
+			if items == nil {
+				items = make(schema)
+			}
+			items["enum"] = enum
+			base["items"] = items
+		} else {
+			base["enum"] = enum
+		}
+	}
+	return base, nil
+}
+
+// merge applies an override to a derived property, or resolves it as a new
+// property when there is none or the override names its own type
+func (g *generator) merge(derived interface{}, override interface{}) (schema, error) {
+	s, ok := asSchema(override)
+	if !ok {
+		return nil, errors.New("expecting a schema object")
+	}
+	existing, ok := asSchema(derived)
+	if !ok || s["go"] != nil || s["ref"] != nil {
+		return g.resolve(s)
+	}
+	return g.apply(existing, s)
+}
+
+// makeNullable adds null to the type of every property, recursively
+func makeNullable(s schema) {
+	properties, _ := asSchema(s["properties"])
+	for _, p := range properties {
+		property, ok := asSchema(p)
+		if !ok {
+			continue
+		}
+		makeNullable(property)
+		if t, ok := property["type"].(string); ok {
+			property["type"] = []interface{}{t, "null"}
+		}
+	}
+}
+
+// filterProperties keeps the properties, and required names, that pass
+func filterProperties(s schema, keep func(string) bool) {
+	if properties, found := asSchema(s["properties"]); found {
+		for name := range properties {
+			if !keep(name) {
+				delete(properties, name)
+			}
+		}
+	}
+	if required, found := s["required"].([]interface{}); found {
+		kept := make([]interface{}, 0, len(required))
+		for _, name := range required {
+			if keep(fmt.Sprint(name)) {
+				kept = append(kept, name)
+			}
+		}
+		s["required"] = kept
+	}
+}
+
+/*********************************  Go types ****************************/
+
+// goSchema derives the schema of a Go type expression
+func (g *generator) goSchema(expr ast.Expr) (schema, error) {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return g.goSchema(e.X)
+	case *ast.ArrayType:
+		items, err := g.goSchema(e.Elt)
+		if err != nil {
+			return nil, err
+		}
+		return schema{"items": items, "type": "array"}, nil
+	case *ast.MapType:
+		return schema{"properties": schema{}, "type": "object"}, nil
+	case *ast.InterfaceType:
+		return schema{}, nil
+	case *ast.StructType:
+		return g.structSchema(e)
+	case *ast.SelectorExpr:
+		if x, ok := e.X.(*ast.Ident); ok && x.Name == "time" && e.Sel.Name == "Time" {
+			return schema{"format": "date-time", "type": "string"}, nil
+		}
+		if x, ok := e.X.(*ast.Ident); ok && x.Name == "json" && e.Sel.Name == "RawMessage" {
+			return schema{}, nil
+		}
+	case *ast.Ident:
+		switch e.Name {
+		case "string":
+			return schema{"type": "string"}, nil
+		case "bool":
+			return schema{"type": "boolean"}, nil
+		case "float32", "float64":
+			return schema{"type": "number"}, nil
+		case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+			return schema{"type": "integer"}, nil
+		}
+		return g.namedSchema(e.Name)
+	}
+	return nil, fmt.Errorf("no schema for Go type %T", expr)
+}
+
+// namedSchema derives the schema of a type declared by the contract and
+// applies its overrides from api.json
+func (g *generator) namedSchema(name string) (schema, error) {
+	expr, found := g.types[name]
+	if !found {
+		return nil, errors.New("unknown Go type " + name)
+	}
+	if g.active[name] {
+		// a type that contains itself, such as a tree
+		return schema{"description": "A nested " + name + " of the same shape."}, nil
+	}
+	g.active[name] = true
+	defer delete(g.active, name)
+
+	s, err := g.goSchema(expr)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	if override, found := g.api.Types[name]; found {
+		s, err = g.apply(s, deepCopy(override).(schema))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return s, nil
+}
+
+// structSchema describes each exported field under its JSON name, using its
+// comment as the description
+func (g *generator) structSchema(st *ast.StructType) (schema, error) {
+	properties := make(schema)
+	for _, field := range st.Fields.List {
+		for _, ident := range field.Names {
+			if !ident.IsExported() {
+				continue
+			}
+			name := ident.Name
+			if field.Tag != nil {
+				tag, err := strconv.Unquote(field.Tag.Value)
+				if err != nil {
+					return nil, err
+				}
+				jsonName := strings.Split(reflect.StructTag(tag).Get("json"), ",")[0]
+				if jsonName == "-" {
+					continue
+				}
+				if jsonName != "" {
+					name = jsonName
+				}
+			}
+			property, err := g.goSchema(field.Type)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %v", ident.Name, err)
+			}
+			if comment := fieldComment(field); comment != "" {
+				property["description"] = comment
+			}
+			properties[name] = property
+		}
+	}
+	return schema{"properties": properties, "type": "object"}, nil
+}
+
+func fieldComment(field *ast.Field) string {
+	for _, group := range []*ast.CommentGroup{field.Comment, field.Doc} {
+		if group != nil {
+			if text := strings.TrimSpace(group.Text()); text != "" {
+				return strings.Join(strings.Fields(text), " ")
+			}
+		}
+	}
+	return ""
+}
+
+// enumValues lists the strings in the literal of a variable, the values of
+// a map or the named field of a slice of structs
+func (g *generator) enumValues(from string) ([]interface{}, error) {
+	parts := strings.SplitN(from, ".", 2)
+	lit, found := g.values[parts[0]]
+	if !found {
+		return nil, errors.New("enumFrom: no literal variable " + parts[0])
+	}
+	var enum []interface{}
+	for _, elt := range lit.Elts {
+		var value ast.Expr
+		switch e := elt.(type) {
+		case *ast.KeyValueExpr:
+			value = e.Value
+		case *ast.CompositeLit:
+			if len(parts) < 2 {
+				return nil, errors.New("enumFrom: " + from + " needs the field to take")
+			}
+			for _, f := range e.Elts {
+				if kv, ok := f.(*ast.KeyValueExpr); ok {
+					if key, ok := kv.Key.(*ast.Ident); ok && key.Name == parts[1] {
+						value = kv.Value
+					}
+				}
+			}
+		}
+		basic, ok := value.(*ast.BasicLit)
+		if !ok || basic.Kind != token.STRING {
+			return nil, errors.New("enumFrom: " + from + " has a value that is not a string literal")
+		}
+		s, err := strconv.Unquote(basic.Value)
+		if err != nil {
+			return nil, err
+		}
+		enum = append(enum, s)
+	}
+	return enum, nil
+}
+
+/*********************************  samples ****************************/
+
+func (g *generator) samples() (schema, error) {
+	samples := make(schema)
+	for _, name := range sortedKeys(g.api.Samples) {
+		spec := g.api.Samples[name]
+		s, err := g.resolve(spec.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("sample %s: %v", name, err)
+		}
+		sample := sampleValue(s)
+		if values, ok := sample.(map[string]interface{}); ok {
+			for key, value := range spec.Values {
+				values[key] = value
+			}
+		}
+		samples[name] = sample
+	}
+	return samples, nil
+}
+
+// sampleValue makes up a value that the schema accepts
+func sampleValue(s schema) interface{} {
+	if enum, found := s["enum"].([]interface{}); found && len(enum) > 0 {
+		return enum[0]
+	}
+	switch sampleType(s) {
+	case "string":
+		if s["format"] == "date-time" {
+			return sampleTimestamp
+		}
+		if description, found := s["description"].(string); found {
+			return description
+		}
+		return ""
+	case "number":
+		return 123.456
+	case "integer":
+		return 0
+	case "boolean":
+		return false
+	case "array":
+		items, _ := asSchema(s["items"])
+		if value := sampleValue(items); value != nil {
+			return []interface{}{value}
+		}
+		return []interface{}{}
+	case "object":
+		object := make(map[string]interface{})
+		properties, _ := asSchema(s["properties"])
+		for name, p := range properties {
+			if property, ok := asSchema(p); ok {
+				if value := sampleValue(property); value != nil {
+					object[name] = value
+				}
+			}
+		}
+		return object
+	}
+	return nil
+}
+
+// sampleType is the type of the schema, the first one that is not null when
+// there are several
+func sampleType(s schema) string {
+	if list, ok := s["type"].([]interface{}); ok {
+		for _, t := range list {
+			if t != "null" {
+				return fmt.Sprint(t)
+			}
+		}
+		return "null"
+	}
+	t, _ := s["type"].(string)
+	return t
+}
+
+/*********************************  output ****************************/
+
+// writeGoString writes a Go source file declaring a string variable that
+// holds the indented JSON encoding of the value
+func writeGoString(path string, variable string, value interface{}) error {
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "    ")
+	err := enc.Encode(value)
+	if err != nil {
+		return err
+	}
+	content := strings.TrimSuffix(buf.String(), "\n")
+	if strings.Contains(content, "`") {
+		return errors.New(path + ": a backquote cannot be written to a raw string")
+	}
+	source := "// Code generated by scripts/generate_go_schema.go from the contract types and " +
+		apiDescriptionFile + ". DO NOT EDIT.\n\n" +
+		"package main\n\nvar " + variable + " = `\n" + content + "`\n"
+	return ioutil.WriteFile(path, []byte(source), 0644)
+}
+
+// asSchema accepts both derived schemas and objects decoded from api.json
+func asSchema(v interface{}) (schema, bool) {
+	switch x := v.(type) {
+	case schema:
+		return x, true
+	case map[string]interface{}:
+		return schema(x), true
+	}
+	return nil, false
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	for _, key := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, key.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// deepCopy copies decoded JSON, turning its objects into schemas
+func deepCopy(v interface{}) interface{} {
+	switch x := v.(type) {
+	case schema:
+		c := make(schema, len(x))
+		for key, value := range x {
+			c[key] = deepCopy(value)
+		}
+		return c
+	case map[string]interface{}:
+		c := make(schema, len(x))
+		for key, value := range x {
+			c[key] = deepCopy(value)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(x))
+		for i, value := range x {
+			c[i] = deepCopy(value)
+		}
+		return c
+	}
+	return v
+}
@@ -0,0 +1,470 @@
+//go:build simulator
+// +build simulator
+
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+// Local chaincode simulator
+//
+// Built with the simulator tag, the contract runs as a command line tool
+// instead of a chaincode, against a world state kept in a JSON file, so that
+// its functions can be tried without a peer. Run it from the contract
+// directory with
+//     go run -tags simulator . [flags] init ARG...
+//     go run -tags simulator . [flags] invoke FUNCTION ARG...
+//     go run -tags simulator . [flags] query FUNCTION ARG...
+// where every ARG is one JSON string argument, and the flags are
+//     -ledger FILE  the world state, created on first use (simulator-ledger.json)
+//     -as NAME      common name of the caller's certificate (admin)
+//     -roles LIST   comma separated role attribute of the caller (admin)
+//     -time TS      RFC3339 transaction timestamp (now)
+//
+// Every call is one transaction, as on a peer: reads see the state committed
+// by earlier calls only and the writes are committed when the call succeeds.
+// A query runs the same way but never commits. Each call prints its payload,
+// the event it set and the keys it added, changed or deleted. The ledger
+// holds JSON values only, which every state of the contract is.
+//
+// simple_contract0.6/simulator.go is generated from this file by go generate.
+
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// SIMULATORLEDGER is the ledger file used when none is given
+const SIMULATORLEDGER string = "simulator-ledger.json"
+
+// SIMULATORMSPID is the MSP of every caller
+const SIMULATORMSPID string = "Org1MSP"
+
+// SIMULATORCHANNEL is the channel the contract sees
+const SIMULATORCHANNEL string = "simulator"
+
+// simulatorAttributeOID is the certificate extension in which the CA puts
+// attributes
+var simulatorAttributeOID = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 7, 8, 1}
+
+// simulatorLedger is the content of the ledger file
+type simulatorLedger struct {
+	TxCount int                        `json:"txCount"` // transactions committed so far
+	State   map[string]json.RawMessage `json:"state"`   // world state by key
+}
+
+// simulatorStub is a shim.ChaincodeStubInterface over the ledger file. The
+// functions the contracts do not use are left to the nil interface it
+// embeds and panic.
+type simulatorStub struct {
+	shim.ChaincodeStubInterface
+
+	ledger  *simulatorLedger
+	writes  map[string][]byte // writes of the transaction, nil deletes
+	args    []string          // function and arguments
+	txID    string
+	txTime  time.Time
+	creator []byte
+	event   *pb.ChaincodeEvent // last event set
+}
+
+// simulatorTools are the commands that other files built with the simulator
+// tag add to init, invoke and query, each runs with the arguments that
+// follow its name and returns the exit status
+var simulatorTools = map[string]func(cc shim.Chaincode, args []string, stdout io.Writer, stderr io.Writer) int{}
+
+func main() {
+	os.Exit(simulate(new(SimpleChaincode), os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// simulate runs one call of the command line against the ledger file and
+// returns the exit status
+func simulate(cc shim.Chaincode, arguments []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("simulator", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	ledgerFile := flags.String("ledger", SIMULATORLEDGER, "JSON file holding the world state")
+	caller := flags.String("as", "admin", "common name of the caller's certificate")
+	roles := flags.String("roles", "admin", "comma separated role attribute of the caller, none when empty")
+	txTime := flags.String("time", "", "RFC3339 transaction timestamp, now when empty")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: simulator [flags] init ARG...")
+		fmt.Fprintln(stderr, "       simulator [flags] invoke FUNCTION ARG...")
+		fmt.Fprintln(stderr, "       simulator [flags] query FUNCTION ARG...")
+		tools := make([]string, 0, len(simulatorTools))
+		for name := range simulatorTools {
+			tools = append(tools, name)
+		}
+		sort.Strings(tools)
+		for _, name := range tools {
+			fmt.Fprintln(stderr, "       simulator "+name+" -h")
+		}
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(arguments); err != nil {
+		return 2
+	}
+	command := flags.Arg(0)
+	args := flags.Args()
+	if tool, found := simulatorTools[command]; found {
+		return tool(cc, args[1:], stdout, stderr)
+	}
+	switch {
+	case command == "init" && len(args) >= 1:
+		args = append([]string{"init"}, args[1:]...)
+	case (command == "invoke" || command == "query") && len(args) >= 2:
+		args = args[1:]
+	default:
+		flags.Usage()
+		return 2
+	}
+
+	err := simulateCall(cc, command, args, *ledgerFile, *caller, *roles, *txTime, stdout)
+	if err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return 1
+	}
+	return 0
+}
+
+// simulateCall runs the call as the next transaction on the ledger file,
+// prints its outcome and, for init and invoke, commits it
+func simulateCall(cc shim.Chaincode, command string, args []string, ledgerFile string, caller string, roles string, txTime string, out io.Writer) error {
+	ledger, err := loadSimulatorLedger(ledgerFile)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	if txTime != "" {
+		now, err = time.Parse(time.RFC3339Nano, txTime)
+		if err != nil {
+			return errors.New("The transaction time is not an RFC3339 timestamp: " + txTime)
+		}
+	}
+	stub, err := newSimulatorStub(ledger, args, caller, roles, now)
+	if err != nil {
+		return err
+	}
+	resp := stub.execute(cc, command == "init")
+	if resp.Status != shim.OK {
+		return errors.New(resp.Message)
+	}
+
+	fmt.Fprintln(out, command, stub.txID, "at", stub.txTime.Format(time.RFC3339Nano))
+	if len(resp.Payload) > 0 {
+		fmt.Fprintln(out, "payload:")
+		fmt.Fprintln(out, indentJSON(resp.Payload))
+	}
+	if stub.event != nil {
+		fmt.Fprintln(out, "event", stub.event.EventName+":")
+		fmt.Fprintln(out, indentJSON(stub.event.Payload))
+	}
+	changes := stub.printStateDiff(out)
+	if command == "query" {
+		if changes > 0 {
+			fmt.Fprintln(out, "query, the changes above are not committed")
+		}
+		return nil
+	}
+	stub.commit()
+	return ledger.save(ledgerFile)
+}
+
+// loadSimulatorLedger reads the ledger file, a missing file is an empty
+// ledger
+func loadSimulatorLedger(ledgerFile string) (*simulatorLedger, error) {
+	ledger := &simulatorLedger{}
+
+	ledgerBytes, err := os.ReadFile(ledgerFile)
+	if err == nil {
+		err = json.Unmarshal(ledgerBytes, ledger)
+		if err != nil {
+			return nil, errors.New("Unable to unmarshal ledger file " + ledgerFile + ": " + fmt.Sprint(err))
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, errors.New("Unable to read ledger file: " + fmt.Sprint(err))
+	}
+	if ledger.State == nil {
+		ledger.State = make(map[string]json.RawMessage)
+	}
+	return ledger, nil
+}
+
+// save writes the ledger file aside and renames it, so that a failed write
+// leaves the old file
+func (l *simulatorLedger) save(ledgerFile string) error {
+	ledgerBytes, err := json.MarshalIndent(l, "", "    ")
+	if err != nil {
+		return errors.New("Marshal failed for ledger file: " + fmt.Sprint(err))
+	}
+	err = os.WriteFile(ledgerFile+".tmp", append(ledgerBytes, '\n'), 0644)
+	if err == nil {
+		err = os.Rename(ledgerFile+".tmp", ledgerFile)
+	}
+	if err != nil {
+		return errors.New("Unable to write ledger file: " + fmt.Sprint(err))
+	}
+	return nil
+}
+
+// newSimulatorStub prepares the next transaction on the ledger
+func newSimulatorStub(ledger *simulatorLedger, args []string, caller string, roles string, txTime time.Time) (*simulatorStub, error) {
+	var err error
+
+	stub := &simulatorStub{
+		ledger: ledger,
+		writes: make(map[string][]byte),
+		args:   args,
+		txID:   fmt.Sprintf("tx%06d", ledger.TxCount+1),
+		txTime: txTime,
+	}
+	stub.creator, err = simulatorCreator(caller, roles, txTime)
+	if err != nil {
+		return nil, errors.New("Unable to create the identity of " + caller + ": " + fmt.Sprint(err))
+	}
+	return stub, nil
+}
+
+// execute runs the transaction through Init or Invoke
+func (s *simulatorStub) execute(cc shim.Chaincode, init bool) pb.Response {
+	if init {
+		return cc.Init(s)
+	}
+	return cc.Invoke(s)
+}
+
+// commit applies the writes of the transaction to the ledger
+func (s *simulatorStub) commit() {
+	for key, value := range s.writes {
+		if value == nil {
+			delete(s.ledger.State, key)
+			continue
+		}
+		s.ledger.State[key] = json.RawMessage(value)
+	}
+	s.ledger.TxCount++
+}
+
+// printStateDiff prints the keys that the transaction adds (+), changes (~)
+// or deletes (-) and returns their number
+func (s *simulatorStub) printStateDiff(out io.Writer) int {
+	keys := make([]string, 0, len(s.writes))
+	for key := range s.writes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	changes := 0
+	for _, key := range keys {
+		before, found := s.ledger.State[key]
+		after := s.writes[key]
+		switch {
+		case !found && after == nil:
+			continue
+		case !found:
+			fmt.Fprintln(out, "+", key, compactJSON(after))
+		case after == nil:
+			fmt.Fprintln(out, "-", key, compactJSON(before))
+		case compactJSON(before) == compactJSON(after):
+			continue
+		default:
+			fmt.Fprintln(out, "~", key)
+			fmt.Fprintln(out, "    before:", compactJSON(before))
+			fmt.Fprintln(out, "    after: ", compactJSON(after))
+		}
+		changes++
+	}
+	if changes == 0 {
+		fmt.Fprintln(out, "no state changes")
+	}
+	return changes
+}
+
+func indentJSON(value []byte) string {
+	var buf bytes.Buffer
+
+	if json.Indent(&buf, value, "", "    ") != nil {
+		return string(value)
+	}
+	return buf.String()
+}
+
+func compactJSON(value []byte) string {
+	var buf bytes.Buffer
+
+	if json.Compact(&buf, value) != nil {
+		return string(value)
+	}
+	return buf.String()
+}
+
+/*********************************  shim.ChaincodeStubInterface ****************************/
+
+func (s *simulatorStub) GetArgs() [][]byte {
+	args := make([][]byte, len(s.args))
+	for i, arg := range s.args {
+		args[i] = []byte(arg)
+	}
+	return args
+}
+
+func (s *simulatorStub) GetStringArgs() []string {
+	return s.args
+}
+
+func (s *simulatorStub) GetFunctionAndParameters() (string, []string) {
+	if len(s.args) == 0 {
+		return "", []string{}
+	}
+	return s.args[0], s.args[1:]
+}
+
+func (s *simulatorStub) GetTxID() string {
+	return s.txID
+}
+
+func (s *simulatorStub) GetChannelID() string {
+	return SIMULATORCHANNEL
+}
+
+func (s *simulatorStub) GetTxTimestamp() (*timestamppb.Timestamp, error) {
+	return timestamppb.New(s.txTime), nil
+}
+
+func (s *simulatorStub) GetCreator() ([]byte, error) {
+	return s.creator, nil
+}
+
+func (s *simulatorStub) GetState(key string) ([]byte, error) {
+	return s.ledger.State[key], nil
+}
+
+func (s *simulatorStub) PutState(key string, value []byte) error {
+	if key == "" {
+		return errors.New("key must not be an empty string")
+	}
+	if len(value) == 0 {
+		// the peer treats an empty value as a delete
+		s.writes[key] = nil
+		return nil
+	}
+	if !json.Valid(value) {
+		return errors.New("the simulator ledger holds JSON values only, " + key + " is not one")
+	}
+	s.writes[key] = value
+	return nil
+}
+
+func (s *simulatorStub) DelState(key string) error {
+	if key == "" {
+		return errors.New("key must not be an empty string")
+	}
+	s.writes[key] = nil
+	return nil
+}
+
+// GetStateByRange returns the committed keys in [startKey, endKey) in key
+// order, an empty endKey leaves the range open
+func (s *simulatorStub) GetStateByRange(startKey string, endKey string) (shim.StateQueryIteratorInterface, error) {
+	iter := &simulatorIterator{}
+	for key := range s.ledger.State {
+		if key >= startKey && (endKey == "" || key < endKey) {
+			iter.keys = append(iter.keys, key)
+		}
+	}
+	sort.Strings(iter.keys)
+	for _, key := range iter.keys {
+		iter.values = append(iter.values, s.ledger.State[key])
+	}
+	return iter, nil
+}
+
+func (s *simulatorStub) SetEvent(name string, payload []byte) error {
+	if name == "" {
+		return errors.New("event name can not be empty string")
+	}
+	s.event = &pb.ChaincodeEvent{TxId: s.txID, EventName: name, Payload: payload}
+	return nil
+}
+
+// simulatorIterator walks a snapshot of a key range
+type simulatorIterator struct {
+	keys   []string
+	values [][]byte
+	next   int
+}
+
+func (it *simulatorIterator) HasNext() bool {
+	return it.next < len(it.keys)
+}
+
+func (it *simulatorIterator) Next() (*queryresult.KV, error) {
+	if !it.HasNext() {
+		return nil, errors.New("the iterator is exhausted")
+	}
+	it.next++
+	return &queryresult.KV{Key: it.keys[it.next-1], Value: it.values[it.next-1]}, nil
+}
+
+func (it *simulatorIterator) Close() error {
+	return nil
+}
+
+// simulatorCreator returns the serialized identity of the caller, with a
+// self signed certificate in which the roles are a CA attribute
+func simulatorCreator(name string, roles string, now time.Time) ([]byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name, Organization: []string{SIMULATORMSPID}},
+		NotBefore:    now.AddDate(-1, 0, 0),
+		NotAfter:     now.AddDate(1, 0, 0),
+	}
+	if strings.TrimSpace(roles) != "" {
+		attrs, err := json.Marshal(map[string]interface{}{"attrs": map[string]string{"role": roles}})
+		if err != nil {
+			return nil, err
+		}
+		template.ExtraExtensions = []pkix.Extension{{Id: simulatorAttributeOID, Value: attrs}}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(&msp.SerializedIdentity{
+		Mspid:   SIMULATORMSPID,
+		IdBytes: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	})
+}
@@ -0,0 +1,73 @@
+//go:build simulator
+// +build simulator
+
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSimulate(t *testing.T) {
+	ledgerFile := filepath.Join(t.TempDir(), "ledger.json")
+	steps := []struct {
+		args       string
+		wantStatus int
+		wantOut    []string
+		wantErr    string
+	}{
+		{`init {"version":"1.1"} {"tradeID":"0476219"}`, 0, []string{"init tx000001 at", "+ ContractStateKey", "+ TradeStateKey"}, ""},
+		{`-time 2017-03-20T08:00:00Z invoke createAsset {"assetID":"T1","assetType":"CrudeTank","carrier":"alpha","maxTemperature":20}`, 0,
+			[]string{"invoke tx000002 at 2017-03-20T08:00:00Z", `+ T1 {"assetID":"T1"`}, ""},
+		{`invoke updateAsset {"assetID":"T1","maxTemperature":80}`, 0, []string{"~ T1", `before: {"assetID":"T1"`, `"active":["OVERTEMP"]`}, ""},
+		{`-as dev -roles device invoke deleteAsset {"assetID":"T1"}`, 1, nil, "error: Permission denied: deleteAsset"},
+		{`-as alpha -roles carrier invoke deleteAsset {"assetID":"T1","reason":"test"}`, 0, []string{"invoke tx000004", "event assetDeleted:", "+ Tombstone:T1"}, ""},
+		{`query readDeletedAssets`, 0, []string{"query tx000005", "payload:", "no state changes"}, ""},
+		{`invoke`, 2, nil, "usage: simulator"},
+		{`-time yesterday query readContractState`, 1, nil, "error: The transaction time is not an RFC3339 timestamp: yesterday"},
+	}
+	for i, step := range steps {
+		var stdout, stderr bytes.Buffer
+		args := append([]string{"-ledger", ledgerFile}, strings.Split(step.args, " ")...)
+		status := simulate(new(SimpleChaincode), args, &stdout, &stderr)
+		if status != step.wantStatus {
+			t.Fatalf("step %d: status %d, expected %d, stderr %s", i, status, step.wantStatus, stderr.String())
+		}
+		for _, want := range step.wantOut {
+			if !strings.Contains(stdout.String(), want) {
+				t.Fatalf("step %d: output does not contain %q:\n%s", i, want, stdout.String())
+			}
+		}
+		if !strings.Contains(stderr.String(), step.wantErr) {
+			t.Fatalf("step %d: stderr does not contain %q:\n%s", i, step.wantErr, stderr.String())
+		}
+	}
+
+	// the failed calls and the query did not commit
+	var ledger simulatorLedger
+	ledgerBytes, err := os.ReadFile(ledgerFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	json.Unmarshal(ledgerBytes, &ledger)
+	if ledger.TxCount != 4 {
+		t.Fatalf("%d transactions committed, expected 4", ledger.TxCount)
+	}
+	if _, found := ledger.State["T1"]; !found {
+		t.Fatal("the tombstoned asset is not in the ledger file")
+	}
+}
@@ -0,0 +1,210 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+// Alert suppression for maintenance windows
+//
+// While a suppression is in force the asset keeps recording its readings, but
+// the suppressed alerts are not raised by the rules and do not count against
+// compliance. Suppressions are never removed from the ledger, so the list
+// stored for an asset is also the audit trail of who suppressed what and why.
+// Only admins and inspectors may suppress alerts, a carrier could otherwise
+// clear the breaches of the assets in its charge.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// ALERTSUPPRESSIONSKEYPREFIX prefixes the asset ID to store its suppressions
+const ALERTSUPPRESSIONSKEYPREFIX string = "AlertSuppressions:"
+
+// AlertSuppression keeps the named alerts of an asset from being raised while
+// the transaction timestamp lies within [from, to)
+type AlertSuppression struct {
+	AssetID      string         `json:"assetID"`
+	Alerts       AlertNameArray `json:"alerts"`
+	From         string         `json:"from"`
+	To           string         `json:"to"`
+	Reason       string         `json:"reason"`
+	RequestedBy  string         `json:"requestedBy"`  // caller that requested the suppression
+	TxnID        string         `json:"txnuuid"`      // transaction that recorded the suppression
+	TxnTimestamp string         `json:"txntimestamp"` // transaction timestamp of the request
+}
+
+//******************** suppressAlerts ********************/
+
+func (t *SimpleChaincode) suppressAlerts(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	var suppression AlertSuppression
+	var err error
+
+	// the alerts list does not fit the alerts property of an asset state, so
+	// the request is read as a suppression rather than through validateInput
+	if len(args) != 1 {
+		return nil, errors.New("Incorrect number of arguments. Expecting a JSON strings with mandatory assetID")
+	}
+	err = json.Unmarshal([]byte(args[0]), &suppression)
+	if err != nil {
+		return nil, errors.New("Unable to unmarshal suppression JSON data")
+	}
+	suppression.AssetID = strings.TrimSpace(suppression.AssetID)
+	if suppression.AssetID == "" {
+		return nil, errors.New("Asset id is mandatory in the input JSON data")
+	}
+
+	assetBytes, err := getAssetBytes(stub, suppression.AssetID)
+	if err != nil || len(assetBytes) == 0 {
+		return nil, errors.New("Asset does not exist!")
+	}
+	deleted, err := t.isDeleted(stub, suppression.AssetID)
+	if err != nil {
+		return nil, err
+	}
+	if deleted {
+		return nil, errors.New("Asset does not exist!")
+	}
+	if len(suppression.Alerts) == 0 {
+		return nil, errors.New("At least one alert name is required")
+	}
+	for _, name := range suppression.Alerts {
+		if _, found := AlertsValue[name]; !found {
+			return nil, errors.New("Unknown alert: " + name)
+		}
+	}
+	if strings.TrimSpace(suppression.Reason) == "" {
+		return nil, errors.New("A reason is required to suppress alerts")
+	}
+
+	txnTime, err := txnTimestamp(stub)
+	if err != nil {
+		return nil, errors.New("Unable to get transaction timestamp: " + fmt.Sprint(err))
+	}
+	if suppression.From == "" {
+		// the window starts with this transaction
+		suppression.From = txnTime
+	}
+	from, err := time.Parse(time.RFC3339Nano, suppression.From)
+	if err != nil {
+		return nil, errors.New("Suppression 'from' is not an RFC3339 timestamp: " + suppression.From)
+	}
+	to, err := time.Parse(time.RFC3339Nano, suppression.To)
+	if err != nil {
+		return nil, errors.New("Suppression 'to' is not an RFC3339 timestamp: " + suppression.To)
+	}
+	if !to.After(from) {
+		return nil, errors.New("Suppression 'to' must be later than 'from'")
+	}
+	suppression.From = from.UTC().Format(time.RFC3339Nano)
+	suppression.To = to.UTC().Format(time.RFC3339Nano)
+
+	suppression.RequestedBy, err = callerIdentity(stub)
+	if err != nil {
+		return nil, err
+	}
+	suppression.TxnID = stub.GetTxID()
+	suppression.TxnTimestamp = txnTime
+
+	suppressions, err := t.getAlertSuppressions(stub, suppression.AssetID)
+	if err != nil {
+		return nil, err
+	}
+	suppressions = append(suppressions, suppression)
+	suppressionsJSON, err := json.Marshal(suppressions)
+	if err != nil {
+		return nil, errors.New("Marshal failed for alert suppressions" + fmt.Sprint(err))
+	}
+	err = stub.PutState(ALERTSUPPRESSIONSKEYPREFIX+suppression.AssetID, suppressionsJSON)
+	if err != nil {
+		return nil, errors.New("PUT ledger state failed: " + fmt.Sprint(err))
+	}
+
+	suppressionJSON, err := json.Marshal(suppression)
+	if err != nil {
+		return nil, errors.New("Marshal failed for alert suppression" + fmt.Sprint(err))
+	}
+	err = stub.SetEvent("alertsSuppressed", suppressionJSON)
+	if err != nil {
+		return nil, errors.New("Unable to set alertsSuppressed event: " + fmt.Sprint(err))
+	}
+	return nil, nil
+}
+
+//********************readAlertSuppressions********************/
+
+func (t *SimpleChaincode) readAlertSuppressions(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	// validate input data for number of args, Unmarshaling to asset state and obtain asset id
+	stateIn, err := t.validateInput(args)
+	if err != nil {
+		return nil, err
+	}
+	suppressions, err := t.getAlertSuppressions(stub, *stateIn.AssetID)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(suppressions)
+}
+
+// getAlertSuppressions returns every suppression recorded for the asset
+func (t *SimpleChaincode) getAlertSuppressions(stub shim.ChaincodeStubInterface, assetID string) ([]AlertSuppression, error) {
+	var suppressions = make([]AlertSuppression, 0)
+
+	suppressionsBytes, err := stub.GetState(ALERTSUPPRESSIONSKEYPREFIX + assetID)
+	if err != nil {
+		return nil, errors.New("Unable to get alert suppressions from ledger: " + fmt.Sprint(err))
+	}
+	if len(suppressionsBytes) == 0 {
+		return suppressions, nil
+	}
+	err = json.Unmarshal(suppressionsBytes, &suppressions)
+	if err != nil {
+		return nil, errors.New("Unable to unmarshal alert suppressions obtained from ledger")
+	}
+	return suppressions, nil
+}
+
+// activeSuppressions returns the alerts suppressed for the asset at the
+// given transaction timestamp
+func (t *SimpleChaincode) activeSuppressions(stub shim.ChaincodeStubInterface, assetID string, txnTime string) (AlertArrayInternal, error) {
+	var suppressed AlertArrayInternal
+
+	now, err := time.Parse(time.RFC3339Nano, txnTime)
+	if err != nil {
+		return suppressed, errors.New("Invalid transaction timestamp: " + txnTime)
+	}
+	suppressions, err := t.getAlertSuppressions(stub, assetID)
+	if err != nil {
+		return suppressed, err
+	}
+	for _, s := range suppressions {
+		from, err := time.Parse(time.RFC3339Nano, s.From)
+		if err != nil {
+			continue
+		}
+		to, err := time.Parse(time.RFC3339Nano, s.To)
+		if err != nil {
+			continue
+		}
+		if now.Before(from) || !now.Before(to) {
+			continue
+		}
+		for _, name := range s.Alerts {
+			if i, found := AlertsValue[name]; found {
+				suppressed[i] = true
+			}
+		}
+	}
+	return suppressed, nil
+}
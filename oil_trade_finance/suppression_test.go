@@ -0,0 +1,89 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSuppressAlerts(t *testing.T) {
+	m := newTestContract(t)
+	m.mustInvoke("createAsset", `{"assetID":"T1","assetType":"CrudeTank","maxTemperature":20}`)
+	// a window covering the first two updates below, each of them is followed
+	// by a read of the asset
+	to := m.txTime.Add(5 * mockTxInterval).Format(time.RFC3339)
+	m.as("inspector", INSPECTORROLE).mustInvoke("suppressAlerts", `{"assetID":"T1","alerts":["OVERTEMP","TEMPRATE"],"to":"`+to+`","reason":"heating coils cleaned"}`)
+	if m.lastEvent().Name != "alertsSuppressed" {
+		t.Fatalf("event %s, expected alertsSuppressed", m.lastEvent().Name)
+	}
+
+	steps := []struct {
+		event         string
+		wantActive    []string
+		wantCompliant bool
+	}{
+		// within the window the temperature alerts are not raised, the other
+		// rules run
+		{`"maxTemperature":70,"timestamp":"2017-03-01T00:00:00Z"`, nil, true},
+		{`"maxTemperature":70,"sealBroken":true,"timestamp":"2017-03-02T00:00:00Z"`, []string{"SEALTAMPER"}, false},
+		// after it, the overtemp is raised again
+		{`"maxTemperature":70,"timestamp":"2017-03-03T00:00:00Z"`, []string{"OVERTEMP", "SEALTAMPER"}, false},
+	}
+	m.as("admin", ADMINROLE)
+	for i, step := range steps {
+		m.mustInvoke("updateAsset", `{"assetID":"T1",`+step.event+`}`)
+		state := m.asset("T1")
+		if got := activeAlerts(state); !reflect.DeepEqual(got, step.wantActive) || *state.Compliance != step.wantCompliant {
+			t.Fatalf("event %d: active alerts %v, compliant %v, expected %v, %v", i, got, *state.Compliance, step.wantActive, step.wantCompliant)
+		}
+	}
+
+	var suppressions []AlertSuppression
+	json.Unmarshal(m.mustQuery("readAlertSuppressions", `{"assetID":"T1"}`), &suppressions)
+	if len(suppressions) != 1 || suppressions[0].RequestedBy != "inspector" || suppressions[0].To != to ||
+		suppressions[0].From != mockStartTime.Add(2*mockTxInterval).Format(time.RFC3339Nano) {
+		t.Fatalf("unexpected suppressions %+v", suppressions)
+	}
+}
+
+func TestSuppressAlertsRefused(t *testing.T) {
+	tests := []struct {
+		name    string
+		role    string
+		deleted bool
+		args    string
+		wantErr string
+	}{
+		{"unknown alert", ADMINROLE, false, `{"assetID":"T1","alerts":["RUST"],"to":"2030-01-01T00:00:00Z","reason":"r"}`, "RUST"},
+		{"no alerts", ADMINROLE, false, `{"assetID":"T1","alerts":[],"to":"2030-01-01T00:00:00Z","reason":"r"}`, "alerts"},
+		{"no reason", ADMINROLE, false, `{"assetID":"T1","alerts":["OVERTEMP"],"to":"2030-01-01T00:00:00Z","reason":""}`, "A reason is required to suppress alerts"},
+		{"window ends first", ADMINROLE, false, `{"assetID":"T1","alerts":["OVERTEMP"],"from":"2030-01-01T00:00:00Z","to":"2029-01-01T00:00:00Z","reason":"r"}`, "Suppression 'to' must be later than 'from'"},
+		{"bad timestamp", ADMINROLE, false, `{"assetID":"T1","alerts":["OVERTEMP"],"to":"next week","reason":"r"}`, "next week"},
+		{"unknown asset", ADMINROLE, false, `{"assetID":"T9","alerts":["OVERTEMP"],"to":"2030-01-01T00:00:00Z","reason":"r"}`, "Asset does not exist!"},
+		{"deleted asset", ADMINROLE, true, `{"assetID":"T1","alerts":["OVERTEMP"],"to":"2030-01-01T00:00:00Z","reason":"r"}`, "Asset does not exist!"},
+		// the carrier would clear its own breaches
+		{"carrier", CARRIERROLE, false, `{"assetID":"T1","alerts":["OVERTEMP"],"to":"2030-01-01T00:00:00Z","reason":"r"}`, "Permission denied: suppressAlerts requires one of the roles admin, inspector"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestContract(t)
+			m.mustInvoke("createAsset", `{"assetID":"T1","assetType":"CrudeTank"}`)
+			if tt.deleted {
+				m.mustInvoke("deleteAsset", `{"assetID":"T1","reason":"scrapped"}`)
+			}
+			_, err := m.as("alpha", tt.role).invoke("suppressAlerts", tt.args)
+			wantError(t, err, tt.wantErr)
+		})
+	}
+}
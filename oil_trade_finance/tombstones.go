@@ -0,0 +1,231 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+// Soft delete
+//
+// deleteAsset leaves the asset state on the ledger and writes a tombstone
+// next to it recording who deleted it, when and why. While the tombstone
+// exists the asset is hidden from readAsset and cannot be updated.
+// restoreAsset removes the tombstone, purgeAsset removes the asset and all
+// of its records for good and is reserved to admins.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// TOMBSTONEKEYPREFIX prefixes the asset ID to store the tombstone of a deleted asset
+const TOMBSTONEKEYPREFIX string = "Tombstone:"
+
+// Tombstone marks an asset as deleted
+type Tombstone struct {
+	AssetID      string `json:"assetID"`
+	Reason       string `json:"reason,omitempty"`
+	DeletedBy    string `json:"deletedBy"`    // caller that deleted the asset
+	TxnID        string `json:"txnuuid"`      // transaction that deleted the asset
+	TxnTimestamp string `json:"txntimestamp"` // transaction timestamp of the deletion
+}
+
+// assetRecordKeyPrefixes are the prefixes of every record kept for an asset
+// besides its state, all of which go when the asset is purged
+var assetRecordKeyPrefixes = []string{
+	TOMBSTONEKEYPREFIX,
+	ALERTSUPPRESSIONSKEYPREFIX,
+	LATCHEDCLEARANCESKEYPREFIX,
+	CUSTODYCHAINKEYPREFIX,
+	CUSTODYHANDOFFKEYPREFIX,
+	ASSETCHILDRENKEYPREFIX,
+	ASSETDEVICESKEYPREFIX,
+}
+
+//******************** restoreAsset ********************/
+
+func (t *SimpleChaincode) restoreAsset(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	// validate input data for number of args, Unmarshaling to asset state and obtain asset id
+	stateIn, err := t.validateInput(args)
+	if err != nil {
+		return nil, err
+	}
+	assetID := *stateIn.AssetID
+	tombstone, err := t.getTombstone(stub, assetID)
+	if err != nil {
+		return nil, err
+	}
+	if tombstone == nil {
+		return nil, errors.New("Asset " + assetID + " is not deleted")
+	}
+	// only those in charge of the asset may restore it
+	err = t.checkOwnership(stub, assetID, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = stub.DelState(TOMBSTONEKEYPREFIX + assetID)
+	if err != nil {
+		return nil, errors.New("DELSTATE failed! : " + fmt.Sprint(err))
+	}
+
+	restoredBy, err := callerIdentity(stub)
+	if err != nil {
+		return nil, err
+	}
+	eventJSON, err := json.Marshal(map[string]string{
+		"assetID":    assetID,
+		"restoredBy": restoredBy,
+		"txnuuid":    stub.GetTxID(),
+	})
+	if err != nil {
+		return nil, errors.New("Marshal failed for assetRestored event" + fmt.Sprint(err))
+	}
+	err = stub.SetEvent("assetRestored", eventJSON)
+	if err != nil {
+		return nil, errors.New("Unable to set assetRestored event: " + fmt.Sprint(err))
+	}
+	return nil, nil
+}
+
+//******************** purgeAsset ********************/
+
+func (t *SimpleChaincode) purgeAsset(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	// validate input data for number of args, Unmarshaling to asset state and obtain asset id
+	stateIn, err := t.validateInput(args)
+	if err != nil {
+		return nil, err
+	}
+	assetID := *stateIn.AssetID
+	assetBytes, err := stub.GetState(assetID)
+	if err != nil || len(assetBytes) == 0 {
+		return nil, errors.New("Asset does not exist!")
+	}
+	err = t.checkOwnership(stub, assetID, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = t.checkDetached(stub, assetID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Delete the key / asset from the ledger, with everything kept about it
+	err = stub.DelState(assetID)
+	if err != nil {
+		return nil, errors.New("DELSTATE failed! : " + fmt.Sprint(err))
+	}
+	for _, prefix := range assetRecordKeyPrefixes {
+		err = stub.DelState(prefix + assetID)
+		if err != nil {
+			return nil, errors.New("DELSTATE failed! : " + fmt.Sprint(err))
+		}
+	}
+
+	purgedBy, err := callerIdentity(stub)
+	if err != nil {
+		return nil, err
+	}
+	eventJSON, err := json.Marshal(map[string]string{
+		"assetID":  assetID,
+		"purgedBy": purgedBy,
+		"txnuuid":  stub.GetTxID(),
+	})
+	if err != nil {
+		return nil, errors.New("Marshal failed for assetPurged event" + fmt.Sprint(err))
+	}
+	err = stub.SetEvent("assetPurged", eventJSON)
+	if err != nil {
+		return nil, errors.New("Unable to set assetPurged event: " + fmt.Sprint(err))
+	}
+	return nil, nil
+}
+
+//********************readDeletedAssets********************/
+
+func (t *SimpleChaincode) readDeletedAssets(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	var tombstones = make([]Tombstone, 0)
+
+	if len(args) != 0 {
+		return nil, errors.New("Too many arguments. Expecting none.")
+	}
+	_, values, err := getKeysWithPrefix(stub, TOMBSTONEKEYPREFIX)
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range values {
+		var tombstone Tombstone
+		err = json.Unmarshal(value, &tombstone)
+		if err != nil {
+			return nil, errors.New("Unable to unmarshal tombstone obtained from ledger")
+		}
+		tombstones = append(tombstones, tombstone)
+	}
+	return json.Marshal(tombstones)
+}
+
+/*********************************  internal: tombstones ****************************/
+
+// putTombstone completes the tombstone with the caller and transaction and
+// writes it, which hides the asset
+func (t *SimpleChaincode) putTombstone(stub shim.ChaincodeStubInterface, tombstone Tombstone) error {
+	var err error
+
+	tombstone.DeletedBy, err = callerIdentity(stub)
+	if err != nil {
+		return err
+	}
+	tombstone.TxnID = stub.GetTxID()
+	tombstone.TxnTimestamp, err = txnTimestamp(stub)
+	if err != nil {
+		return errors.New("Unable to get transaction timestamp: " + fmt.Sprint(err))
+	}
+	tombstoneJSON, err := json.Marshal(tombstone)
+	if err != nil {
+		return errors.New("Marshal failed for tombstone" + fmt.Sprint(err))
+	}
+	err = stub.PutState(TOMBSTONEKEYPREFIX+tombstone.AssetID, tombstoneJSON)
+	if err != nil {
+		return errors.New("PUT ledger state failed: " + fmt.Sprint(err))
+	}
+	err = stub.SetEvent("assetDeleted", tombstoneJSON)
+	if err != nil {
+		return errors.New("Unable to set assetDeleted event: " + fmt.Sprint(err))
+	}
+	return nil
+}
+
+// getTombstone returns the tombstone of a deleted asset, nil when the asset
+// is not deleted
+func (t *SimpleChaincode) getTombstone(stub shim.ChaincodeStubInterface, assetID string) (*Tombstone, error) {
+	var tombstone Tombstone
+
+	tombstoneBytes, err := stub.GetState(TOMBSTONEKEYPREFIX + assetID)
+	if err != nil {
+		return nil, errors.New("Unable to get tombstone from ledger: " + fmt.Sprint(err))
+	}
+	if len(tombstoneBytes) == 0 {
+		return nil, nil
+	}
+	err = json.Unmarshal(tombstoneBytes, &tombstone)
+	if err != nil {
+		return nil, errors.New("Unable to unmarshal tombstone obtained from ledger")
+	}
+	return &tombstone, nil
+}
+
+// isDeleted returns true when the asset has a tombstone
+func (t *SimpleChaincode) isDeleted(stub shim.ChaincodeStubInterface, assetID string) (bool, error) {
+	tombstone, err := t.getTombstone(stub, assetID)
+	if err != nil {
+		return false, err
+	}
+	return tombstone != nil, nil
+}
@@ -0,0 +1,140 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDeleteAndRestoreAsset(t *testing.T) {
+	m := newCustodyContract(t)
+	m.mustInvoke("deleteAsset", `{"assetID":"T1","reason":"booked twice"}`)
+	event := m.lastEvent()
+	if event.Name != "assetDeleted" {
+		t.Fatalf("event %s, expected assetDeleted", event.Name)
+	}
+
+	// the asset is hidden but stays on the ledger
+	_, err := m.query("readAsset", `{"assetID":"T1"}`)
+	wantError(t, err, "Asset does not exist!")
+	m.mustQuery("readAsset", `{"assetID":"T1","includeDeleted":true}`)
+	_, err = m.invoke("updateAsset", `{"assetID":"T1","maxTemperature":20}`)
+	wantError(t, err, "Asset T1 is deleted, it must be restored before it can be updated")
+	_, err = m.invoke("deleteAsset", `{"assetID":"T1"}`)
+	wantError(t, err, "Asset T1 is already deleted")
+
+	var tombstones []Tombstone
+	json.Unmarshal(m.mustQuery("readDeletedAssets"), &tombstones)
+	if len(tombstones) != 1 || tombstones[0].AssetID != "T1" || tombstones[0].DeletedBy != "alpha" ||
+		tombstones[0].Reason != "booked twice" || tombstones[0].TxnID != event.TxID {
+		t.Fatalf("unexpected tombstones %+v", tombstones)
+	}
+
+	m.mustInvoke("restoreAsset", `{"assetID":"T1"}`)
+	if m.lastEvent().Name != "assetRestored" {
+		t.Fatalf("event %s, expected assetRestored", m.lastEvent().Name)
+	}
+	if *m.asset("T1").Carrier != "alpha" {
+		t.Fatal("the restored asset lost its state")
+	}
+	_, err = m.invoke("restoreAsset", `{"assetID":"T1"}`)
+	wantError(t, err, "Asset T1 is not deleted")
+	tombstones = nil
+	json.Unmarshal(m.mustQuery("readDeletedAssets"), &tombstones)
+	if len(tombstones) != 0 {
+		t.Fatalf("tombstones left after the restore %+v", tombstones)
+	}
+}
+
+func TestPurgeAsset(t *testing.T) {
+	m := newCustodyContract(t)
+	m.mustInvoke("registerDevice", `{"assetID":"T1","device":"sensor-1"}`)
+	m.mustInvoke("proposeHandoff", `{"assetID":"T1","toCarrier":"beta"}`)
+	m.as("inspector", INSPECTORROLE).mustInvoke("suppressAlerts", `{"assetID":"T1","alerts":["OVERTEMP"],"to":"2030-01-01T00:00:00Z","reason":"r"}`)
+	m.as("alpha", CARRIERROLE).mustInvoke("deleteAsset", `{"assetID":"T1"}`)
+	_, err := m.invoke("purgeAsset", `{"assetID":"T1"}`)
+	wantError(t, err, "Permission denied: purgeAsset requires the admin role")
+
+	m.as("admin", ADMINROLE).mustInvoke("purgeAsset", `{"assetID":"T1"}`)
+	if m.lastEvent().Name != "assetPurged" {
+		t.Fatalf("event %s, expected assetPurged", m.lastEvent().Name)
+	}
+	for key := range m.state {
+		if key == "T1" || strings.HasSuffix(key, ":T1") {
+			t.Errorf("%s was left on the ledger by the purge", key)
+		}
+	}
+	_, err = m.invoke("purgeAsset", `{"assetID":"T1"}`)
+	wantError(t, err, "Asset does not exist!")
+	// the ID is free again
+	m.mustInvoke("createAsset", `{"assetID":"T1","assetType":"CrudeTank"}`)
+	if *m.asset("T1").Version != 1 {
+		t.Fatal("the asset created after the purge does not start over")
+	}
+}
+
+func TestReservedAssetIDs(t *testing.T) {
+	tests := []struct {
+		name    string
+		assetID string
+	}{
+		{"tombstone", TOMBSTONEKEYPREFIX + "T1"},
+		{"contract state", CONTRACTSTATEKEY},
+		{"trade state", TRADESTATEKEY},
+		{"suppressions", ALERTSUPPRESSIONSKEYPREFIX + "T1"},
+		{"latched clearances", LATCHEDCLEARANCESKEYPREFIX + "T1"},
+		{"custody chain", CUSTODYCHAINKEYPREFIX + "T1"},
+		{"custody handoff", CUSTODYHANDOFFKEYPREFIX + "T1"},
+		{"children", ASSETCHILDRENKEYPREFIX + "T1"},
+		{"devices", ASSETDEVICESKEYPREFIX + "T1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newCustodyContract(t)
+			before := make(map[string]string)
+			for key, value := range m.state {
+				before[key] = string(value)
+			}
+			_, err := m.as("admin", ADMINROLE).invoke("createAsset", `{"assetID":"`+tt.assetID+`","assetType":"CrudeTank"}`)
+			wantError(t, err, "Asset id "+tt.assetID+" is reserved for the records of the contract")
+			_, err = m.query("readAsset", `{"assetID":"`+tt.assetID+`"}`)
+			wantError(t, err, "Asset does not exist!")
+			for key, value := range m.state {
+				if before[key] != string(value) {
+					t.Fatalf("%s was written", key)
+				}
+			}
+
+			// the live asset stays visible and not deleted
+			var tombstones []Tombstone
+			json.Unmarshal(m.mustQuery("readDeletedAssets"), &tombstones)
+			if len(tombstones) != 0 {
+				t.Fatalf("unexpected tombstones %+v", tombstones)
+			}
+			m.mustQuery("readAsset", `{"assetID":"T1"}`)
+		})
+	}
+}
+
+func TestDeleteAndRestoreAssetOfAnotherCarrier(t *testing.T) {
+	const refused = "Permission denied: only the carrier alpha of asset T1 or a device registered to it may change it"
+
+	m := newCustodyContract(t)
+	_, err := m.as("beta", CARRIERROLE).invoke("deleteAsset", `{"assetID":"T1"}`)
+	wantError(t, err, refused)
+	m.as("alpha", CARRIERROLE).mustInvoke("deleteAsset", `{"assetID":"T1"}`)
+	_, err = m.as("beta", CARRIERROLE).invoke("restoreAsset", `{"assetID":"T1"}`)
+	wantError(t, err, refused)
+	// an admin manages every asset
+	m.as("admin", ADMINROLE).mustInvoke("restoreAsset", `{"assetID":"T1"}`)
+}
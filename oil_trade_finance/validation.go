@@ -0,0 +1,254 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+// Argument validation
+//
+// The arguments of every function published in the API section of schemas
+// are checked against the schema of the function before it runs, so that a
+// malformed event is refused with the name of each offending field instead
+// of having its unknown properties silently dropped. The checker covers the
+// JSON Schema keywords that schemas uses: type, enum, format date-time,
+// properties, required, additionalProperties, items, minItems and maxItems.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// apiSchemas is the API section of schemas, parsed on first use. The peer
+// runs transactions concurrently, so it is parsed once for all of them.
+var (
+	apiSchemas     map[string]interface{}
+	apiSchemasErr  error
+	apiSchemasOnce sync.Once
+)
+
+// validateArgs checks the arguments of a function against its published
+// schema, functions without one are not checked
+func validateArgs(function string, args []string) error {
+	api, err := getAPISchema(function)
+	if err != nil || api == nil {
+		return err
+	}
+	argsSchema, _ := getObject(api, "properties.args")
+	argsMap, _ := argsSchema.(map[string]interface{})
+	if argsMap == nil {
+		return nil
+	}
+
+	var problems []string
+	if min, found := argsMap["minItems"].(float64); found && float64(len(args)) < min {
+		problems = append(problems, fmt.Sprintf("args: expecting at least %v argument(s), got %d", min, len(args)))
+	}
+	if max, found := argsMap["maxItems"].(float64); found && float64(len(args)) > max {
+		problems = append(problems, fmt.Sprintf("args: expecting at most %v argument(s), got %d", max, len(args)))
+	}
+	items, _ := argsMap["items"].(map[string]interface{})
+	for i, arg := range args {
+		var value interface{}
+		path := "args[" + strconv.Itoa(i) + "]"
+		err = json.Unmarshal([]byte(arg), &value)
+		if err != nil {
+			problems = append(problems, path+": not a JSON encoded value: "+fmt.Sprint(err))
+			continue
+		}
+		problems = validateValue(items, value, path, problems)
+	}
+	if len(problems) > 0 {
+		return errors.New("Invalid arguments for " + function + ": " + strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// validateEvent checks one event of a batch against the argument schema of
+// updateAsset
+func validateEvent(event []byte) error {
+	var value interface{}
+
+	api, err := getAPISchema("updateAsset")
+	if err != nil {
+		return err
+	}
+	items, _ := getObject(api, "properties.args.items")
+	schema, _ := items.(map[string]interface{})
+	err = json.Unmarshal(event, &value)
+	if err != nil {
+		return errors.New("Event is not a JSON encoded value: " + fmt.Sprint(err))
+	}
+	problems := validateValue(schema, value, "", nil)
+	if len(problems) > 0 {
+		return errors.New("Invalid event: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+/*********************************  internal: validation ****************************/
+
+// getAPISchema returns the schema of a published function, nil if the
+// function is not published
+func getAPISchema(function string) (map[string]interface{}, error) {
+	apiSchemasOnce.Do(func() {
+		var all map[string]interface{}
+		err := json.Unmarshal([]byte(schemas), &all)
+		if err != nil {
+			apiSchemasErr = errors.New("Unable to unmarshal schemas: " + fmt.Sprint(err))
+			return
+		}
+		apiSchemas, _ = all["API"].(map[string]interface{})
+	})
+	if apiSchemasErr != nil {
+		return nil, apiSchemasErr
+	}
+	api, _ := apiSchemas[function].(map[string]interface{})
+	return api, nil
+}
+
+// validateValue appends a problem for every way in which the value breaks
+// the schema, each prefixed with the path of the offending field
+func validateValue(schema map[string]interface{}, value interface{}, path string, problems []string) []string {
+	if schema == nil {
+		return problems
+	}
+	if expected, found := schema["type"]; found && !hasSchemaType(expected, value) {
+		return append(problems, fieldPath(path)+": expecting "+fmt.Sprint(expected)+", got "+jsonType(value))
+	}
+	if enum, found := schema["enum"].([]interface{}); found {
+		matched := false
+		for _, allowed := range enum {
+			if reflect.DeepEqual(allowed, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			names := make([]string, len(enum))
+			for i, allowed := range enum {
+				names[i] = fmt.Sprint(allowed)
+			}
+			return append(problems, fieldPath(path)+": "+fmt.Sprint(value)+" is not one of "+strings.Join(names, ", "))
+		}
+	}
+	if format, _ := schema["format"].(string); format == "date-time" {
+		if s, ok := value.(string); ok {
+			if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+				problems = append(problems, fieldPath(path)+": "+s+" is not an RFC3339 timestamp")
+			}
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		if required, found := schema["required"].([]interface{}); found {
+			for _, name := range required {
+				if _, present := v[fmt.Sprint(name)]; !present {
+					problems = append(problems, joinPath(path, fmt.Sprint(name))+": is required")
+				}
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, found := properties[name].(map[string]interface{}); found {
+				problems = validateValue(property, v[name], joinPath(path, name), problems)
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					problems = append(problems, joinPath(path, name)+": is not a known property")
+				}
+			case map[string]interface{}:
+				problems = validateValue(additional, v[name], joinPath(path, name), problems)
+			}
+		}
+	case []interface{}:
+		if min, found := schema["minItems"].(float64); found && float64(len(v)) < min {
+			problems = append(problems, fmt.Sprintf("%s: expecting at least %v item(s), got %d", fieldPath(path), min, len(v)))
+		}
+		if max, found := schema["maxItems"].(float64); found && float64(len(v)) > max {
+			problems = append(problems, fmt.Sprintf("%s: expecting at most %v item(s), got %d", fieldPath(path), max, len(v)))
+		}
+		items, _ := schema["items"].(map[string]interface{})
+		for i := range v {
+			problems = validateValue(items, v[i], path+"["+strconv.Itoa(i)+"]", problems)
+		}
+	}
+	return problems
+}
+
+// hasSchemaType tells whether the value is of the schema type, or of one of
+// the schema types when a list is given
+func hasSchemaType(expected interface{}, value interface{}) bool {
+	if list, ok := expected.([]interface{}); ok {
+		for _, e := range list {
+			if hasSchemaType(e, value) {
+				return true
+			}
+		}
+		return false
+	}
+	actual := jsonType(value)
+	switch expected {
+	case actual:
+		return true
+	case "number":
+		return actual == "integer"
+	}
+	return false
+}
+
+// jsonType names the JSON Schema type of a decoded JSON value
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return reflect.TypeOf(value).String()
+}
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func fieldPath(path string) string {
+	if path == "" {
+		return "event"
+	}
+	return path
+}
@@ -0,0 +1,116 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestValidateArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		function string
+		args     []string
+		wantErr  string
+	}{
+		{"valid", "updateAsset", []string{`{"assetID":"T1","maxTemperature":4,"location":{"latitude":1,"longitude":2}}`}, ""},
+		{"unpublished function", "noSuchFunction", []string{`not even JSON`}, ""},
+		{"too few", "updateAsset", nil, "Invalid arguments for updateAsset: args: expecting at least 1 argument(s), got 0"},
+		{"too many", "updateAsset", []string{`{"assetID":"T1"}`, `{"assetID":"T2"}`}, "args: expecting at most 1 argument(s), got 2"},
+		{"not JSON", "updateAsset", []string{`T1`}, "args[0]: not a JSON encoded value"},
+		{"required", "updateAsset", []string{`{"maxTemperature":4}`}, "args[0].assetID: is required"},
+		{"wrong type", "updateAsset", []string{`{"assetID":"T1","sealBroken":"yes"}`}, "args[0].sealBroken: expecting"},
+		{"nested", "updateAsset", []string{`{"assetID":"T1","location":{"latitude":"north"}}`}, "args[0].location.latitude: expecting"},
+		{"unknown property", "updateAsset", []string{`{"assetID":"T1","colour":"red"}`}, "args[0].colour: is not a known property"},
+		{"bad timestamp", "updateAsset", []string{`{"assetID":"T1","timestamp":"yesterday"}`}, "args[0].timestamp: yesterday is not an RFC3339 timestamp"},
+		{"enum", "createAsset", []string{`{"assetID":"T1","assetType":"Barge"}`}, "args[0].assetType: Barge is not one of CrudeTank, ReeferContainer, Vessel"},
+		{"every problem", "updateAsset", []string{`{"colour":"red","timestamp":"yesterday"}`},
+			"Invalid arguments for updateAsset: args[0].assetID: is required; args[0].colour: is not a known property; args[0].timestamp: yesterday is not an RFC3339 timestamp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wantError(t, validateArgs(tt.function, tt.args), tt.wantErr)
+		})
+	}
+}
+
+func TestValidateEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		event   string
+		wantErr string
+	}{
+		{"valid", `{"assetID":"T1","maxTemperature":4}`, ""},
+		{"not JSON", `{"assetID":`, "Event is not a JSON encoded value"},
+		{"not an object", `["T1"]`, "Invalid event: event: expecting object, got array"},
+		{"wrong type", `{"assetID":"T1","maxTemperature":"hot"}`, "Invalid event: maxTemperature: expecting"},
+		{"calculated property", `{"assetID":"T1","compliant":true}`, "Invalid event: compliant: is not a known property"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wantError(t, validateEvent([]byte(tt.event)), tt.wantErr)
+		})
+	}
+}
+
+func TestValidateValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		value  string
+		want   []string
+	}{
+		{"integer is a number", `{"type":"number"}`, `1`, nil},
+		{"number is not an integer", `{"type":"integer"}`, `1.5`, []string{"v: expecting integer, got number"}},
+		{"type list", `{"type":["string","null"]}`, `null`, nil},
+		{"min items", `{"type":"array","minItems":2}`, `[1]`, []string{"v: expecting at least 2 item(s), got 1"}},
+		{"max items", `{"type":"array","maxItems":1}`, `[1,2]`, []string{"v: expecting at most 1 item(s), got 2"}},
+		{"items", `{"type":"array","items":{"type":"string"}}`, `["a",2]`, []string{"v[1]: expecting string, got integer"}},
+		{"additional properties schema", `{"type":"object","additionalProperties":{"type":"boolean"}}`, `{"a":true,"b":1}`,
+			[]string{"v.b: expecting boolean, got integer"}},
+		{"no schema", `null`, `{"anything":[1]}`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var schema map[string]interface{}
+			var value interface{}
+			json.Unmarshal([]byte(tt.schema), &schema)
+			json.Unmarshal([]byte(tt.value), &value)
+			if got := validateValue(schema, value, "v", nil); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("problems %q, expected %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// transactions validate concurrently from the first one, run with -race
+func TestValidateArgsConcurrently(t *testing.T) {
+	apiSchemas, apiSchemasErr, apiSchemasOnce = nil, nil, sync.Once{}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = validateArgs("updateAsset", []string{`{"assetID":"T1","maxTemperature":4}`})
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("validation %d: %v", i, err)
+		}
+	}
+}
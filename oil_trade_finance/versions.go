@@ -0,0 +1,58 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+// Asset versions
+//
+// Every write of an asset state increments its version, so that a client
+// that read version n can make its update conditional on nobody having
+// written the asset since: updateAsset and deleteAsset take an optional
+// expectedVersion and fail with a conflict when the stored version differs.
+// Assets written before versions were introduced are at version 0.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+)
+
+// VersionCheck is the optional precondition of a request on the version of
+// the stored asset
+type VersionCheck struct {
+	ExpectedVersion *int64 `json:"expectedVersion,omitempty"`
+}
+
+/*********************************  internal: versions ****************************/
+
+// assetVersion returns the version of an asset state, 0 for an asset that
+// does not exist yet or predates versions
+func assetVersion(state AssetState) int64 {
+	if state.Version == nil {
+		return 0
+	}
+	return *state.Version
+}
+
+// checkExpectedVersion fails with a conflict when the request expects a
+// version other than the stored one
+func checkExpectedVersion(requestJSON string, assetID string, stored int64) error {
+	var check VersionCheck
+
+	err := json.Unmarshal([]byte(requestJSON), &check)
+	if err != nil {
+		return errors.New("Unable to unmarshal input JSON data")
+	}
+	if check.ExpectedVersion != nil && *check.ExpectedVersion != stored {
+		return errors.New("Version conflict on asset " + assetID + ": expected version " +
+			strconv.FormatInt(*check.ExpectedVersion, 10) + ", stored version is " + strconv.FormatInt(stored, 10))
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+package main
+
+import (
+	"testing"
+)
+
+func TestExpectedVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		function string
+		args     string
+		wantErr  string
+	}{
+		{"update at the stored version", "updateAsset", `{"assetID":"T1","expectedVersion":2,"maxTemperature":21}`, ""},
+		{"update behind", "updateAsset", `{"assetID":"T1","expectedVersion":1,"maxTemperature":21}`, "Version conflict on asset T1: expected version 1, stored version is 2"},
+		{"update ahead", "updateAsset", `{"assetID":"T1","expectedVersion":3,"maxTemperature":21}`, "Version conflict on asset T1: expected version 3, stored version is 2"},
+		{"create expects nothing stored", "updateAsset", `{"assetID":"T2","assetType":"CrudeTank","expectedVersion":0}`, ""},
+		{"create over an existing asset", "createAsset", `{"assetID":"T1","assetType":"CrudeTank","expectedVersion":0}`, "stored version is 2"},
+		{"delete at the stored version", "deleteAsset", `{"assetID":"T1","expectedVersion":2}`, ""},
+		{"delete behind", "deleteAsset", `{"assetID":"T1","expectedVersion":1}`, "Version conflict on asset T1"},
+		{"no precondition", "updateAsset", `{"assetID":"T1","maxTemperature":21}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestContract(t)
+			m.mustInvoke("createAsset", `{"assetID":"T1","assetType":"CrudeTank","maxTemperature":20}`)
+			m.mustInvoke("updateAsset", `{"assetID":"T1","maxTemperature":20}`)
+			_, err := m.invoke(tt.function, tt.args)
+			wantError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestVersionIncrements(t *testing.T) {
+	m := newFleetContract(t)
+	// attaching writes the child and rolls up the parent, each write is a
+	// new version
+	want := map[string]int64{"V1": 2, "R1": 3, "T1": 2}
+	for id, version := range want {
+		if got := *m.asset(id).Version; got != version {
+			t.Errorf("%s is at version %d, expected %d", id, got, version)
+		}
+	}
+}
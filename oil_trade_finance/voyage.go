@@ -0,0 +1,375 @@
+//go:build simulator
+// +build simulator
+
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+// Voyage generator
+//
+// The voyage command of the simulator generates the sensor events of an
+// asset sailing between two ports, for load tests and demos:
+//     go run -tags simulator . voyage [flags] > voyage.jsonl
+//
+// The asset follows the great circle from port to port at a constant speed
+// and reports a reading at every interval: its location and, when its type
+// declares them, the highest temperature and humidity measured. Readings
+// follow a daily cycle around a base value, peaking in the afternoon of the
+// local solar time, with gaussian noise on top. Some readings are dropped,
+// as by a device out of coverage, and excursions raise the values for a
+// while, as a failing cooling unit would. Given the same flags and seed, the
+// same voyage comes out.
+//
+// The first event creates the asset at departure, the others update it. By
+// default every line is a ReplayEntry, so that the output can be replayed
+// or fed to the simulator; -format args prints the argument JSON only. Every
+// event is checked against the argument schema and the asset type before it
+// is printed.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// EARTHRADIUSNM is the mean radius of the earth in nautical miles
+const EARTHRADIUSNM float64 = 3440.065
+
+// voyagePorts are the ports a voyage can be given by name
+var voyagePorts = map[string][2]float64{
+	"Houston":   {29.7355, -95.0164},
+	"Lagos":     {6.4253, 3.3958},
+	"RasTanura": {26.6444, 50.1617},
+	"Rotterdam": {51.9496, 4.1453},
+	"Santos":    {-23.9608, -46.3000},
+	"Shanghai":  {31.3499, 121.6217},
+	"Singapore": {1.2644, 103.8400},
+}
+
+// voyageProfile is the daily cycle of a reading, the base value of a zero
+// amplitude profile is constant and a NaN base means no reading
+type voyageProfile struct {
+	Base      float64
+	Amplitude float64
+}
+
+// voyageProfiles are the default temperature and humidity profiles by asset
+// type
+var voyageProfiles = map[string][2]voyageProfile{
+	"CrudeTank":       {{40, 3}, {math.NaN(), 0}},
+	"ReeferContainer": {{4, 0.5}, {85, 3}},
+	"Vessel":          {{math.NaN(), 0}, {math.NaN(), 0}},
+}
+
+// voyage holds the settings of a generated voyage
+type voyage struct {
+	AssetID     string
+	AssetType   *AssetType
+	Carrier     string
+	From, To    [2]float64 // latitude and longitude in degrees
+	Start       time.Time
+	Speed       float64 // knots
+	Interval    time.Duration
+	Temperature voyageProfile
+	Humidity    voyageProfile
+	Noise       float64 // standard deviation of the readings
+	Dropout     float64 // probability that a reading is lost
+	Excursions  int
+	ExcursionT  float64 // temperature added during an excursion
+	ExcursionH  float64 // humidity added during an excursion
+	ExcursionD  time.Duration
+	SealBreak   float64 // fraction of the voyage at which the seal breaks, never when 0
+	Seed        int64
+}
+
+func init() {
+	simulatorTools["voyage"] = generateVoyage
+}
+
+// generateVoyage runs the voyage command and returns the exit status
+func generateVoyage(cc shim.Chaincode, arguments []string, stdout io.Writer, stderr io.Writer) int {
+	var v voyage
+
+	ports := make([]string, 0, len(voyagePorts))
+	for name := range voyagePorts {
+		ports = append(ports, name)
+	}
+	sort.Strings(ports)
+	flags := flag.NewFlagSet("voyage", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&v.AssetID, "asset", "T1", "ID of the asset")
+	assetType := flags.String("type", "CrudeTank", "asset type")
+	flags.StringVar(&v.Carrier, "carrier", "", "carrier of the asset, none when empty")
+	from := flags.String("from", "Rotterdam", "port of departure, one of "+strings.Join(ports, ", ")+" or LAT,LON")
+	to := flags.String("to", "Singapore", "port of arrival, as -from")
+	start := flags.String("start", "2017-03-20T08:00:00Z", "RFC3339 time of departure")
+	flags.Float64Var(&v.Speed, "speed", 14, "speed in knots")
+	flags.DurationVar(&v.Interval, "interval", time.Hour, "time between two readings")
+	temperature := flags.Float64("temp", 0, "base temperature in celsius, by default that of the asset type")
+	temperatureAmplitude := flags.Float64("temp-amplitude", 0, "daily temperature swing around the base, by default that of the asset type")
+	humidity := flags.Float64("humidity", 0, "base relative humidity in percent, by default that of the asset type")
+	humidityAmplitude := flags.Float64("humidity-amplitude", 0, "daily humidity swing around the base, by default that of the asset type")
+	flags.Float64Var(&v.Noise, "noise", 0.5, "standard deviation of the noise on every reading")
+	flags.Float64Var(&v.Dropout, "dropout", 0.02, "probability that a reading is lost")
+	flags.IntVar(&v.Excursions, "excursions", 1, "number of excursions")
+	flags.Float64Var(&v.ExcursionT, "excursion-temp", 15, "temperature added during an excursion")
+	flags.Float64Var(&v.ExcursionH, "excursion-humidity", 10, "humidity added during an excursion")
+	flags.DurationVar(&v.ExcursionD, "excursion-length", 6*time.Hour, "duration of an excursion")
+	flags.Float64Var(&v.SealBreak, "seal-break", 0, "fraction of the voyage at which the seal is found broken, never when 0")
+	flags.Int64Var(&v.Seed, "seed", 1, "seed of the random readings")
+	format := flags.String("format", "log", "log for replay log entries, args for the argument JSON only")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: simulator voyage [flags]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(arguments); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 || (*format != "log" && *format != "args") {
+		flags.Usage()
+		return 2
+	}
+
+	err := func() error {
+		var err error
+
+		v.AssetType, err = getAssetType(*assetType)
+		if err != nil {
+			return err
+		}
+		if v.From, err = voyagePort(*from); err != nil {
+			return err
+		}
+		if v.To, err = voyagePort(*to); err != nil {
+			return err
+		}
+		if v.Start, err = time.Parse(time.RFC3339Nano, *start); err != nil {
+			return errors.New("The departure is not an RFC3339 timestamp: " + *start)
+		}
+		if v.Speed <= 0 || v.Interval <= 0 || v.ExcursionD <= 0 {
+			return errors.New("The speed, interval and excursion length must be positive")
+		}
+		if v.Dropout < 0 || v.Dropout >= 1 || v.SealBreak < 0 || v.SealBreak > 1 {
+			return errors.New("The dropout must be in [0, 1) and the seal break in [0, 1]")
+		}
+		// the flags given override the profiles of the asset type
+		profiles := voyageProfiles[v.AssetType.Name]
+		v.Temperature, v.Humidity = profiles[0], profiles[1]
+		flags.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "temp":
+				v.Temperature.Base = *temperature
+			case "temp-amplitude":
+				v.Temperature.Amplitude = *temperatureAmplitude
+			case "humidity":
+				v.Humidity.Base = *humidity
+			case "humidity-amplitude":
+				v.Humidity.Amplitude = *humidityAmplitude
+			}
+		})
+
+		events, err := v.events()
+		if err != nil {
+			return err
+		}
+		for _, entry := range events {
+			var line []byte
+			if *format == "args" {
+				line = entry.Args[0]
+			} else if line, err = json.Marshal(entry); err != nil {
+				return errors.New("Marshal failed for voyage event: " + fmt.Sprint(err))
+			}
+			fmt.Fprintln(stdout, string(line))
+		}
+		return nil
+	}()
+	if err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return 1
+	}
+	return 0
+}
+
+// events generates the voyage as log entries, the first one creating the
+// asset
+func (v *voyage) events() ([]ReplayEntry, error) {
+	var entries []ReplayEntry
+
+	rng := rand.New(rand.NewSource(v.Seed))
+	distance := greatCircleDistance(v.From, v.To)
+	duration := time.Duration(distance / v.Speed * float64(time.Hour))
+	excursions := make([]time.Duration, v.Excursions)
+	for i := range excursions {
+		excursions[i] = time.Duration(rng.Int63n(int64(duration) + 1))
+	}
+	sealBroken := false
+
+	for elapsed := time.Duration(0); ; elapsed += v.Interval {
+		if elapsed > duration {
+			elapsed = duration
+		}
+		now := v.Start.Add(elapsed)
+		fraction := 1.0
+		if duration > 0 {
+			fraction = float64(elapsed) / float64(duration)
+		}
+		position := greatCirclePoint(v.From, v.To, fraction)
+
+		event := map[string]interface{}{
+			"assetID":   v.AssetID,
+			"timestamp": now.Format(time.RFC3339Nano),
+		}
+		function := "updateAsset"
+		if elapsed == 0 {
+			function = "createAsset"
+			event["assetType"] = v.AssetType.Name
+			if v.Carrier != "" {
+				event["carrier"] = v.Carrier
+			}
+		}
+		if v.declares("location") {
+			event["location"] = map[string]float64{"latitude": round(position[0], 4), "longitude": round(position[1], 4)}
+		}
+		excursion := 0.0
+		for _, at := range excursions {
+			if elapsed >= at && elapsed < at+v.ExcursionD {
+				excursion = 1
+			}
+		}
+		if v.declares("maxTemperature") && !math.IsNaN(v.Temperature.Base) {
+			event["maxTemperature"] = round(v.reading(v.Temperature, now, position[1], rng)+excursion*v.ExcursionT, 2)
+		}
+		if v.declares("maxHumidity") && !math.IsNaN(v.Humidity.Base) {
+			humidity := v.reading(v.Humidity, now, position[1], rng) + excursion*v.ExcursionH
+			event["maxHumidity"] = round(math.Max(0, math.Min(100, humidity)), 2)
+		}
+		if v.declares("sealBroken") && v.SealBreak > 0 && !sealBroken && fraction >= v.SealBreak {
+			event["sealBroken"] = true
+			sealBroken = true
+		}
+
+		// the departure and arrival are always reported, and a broken seal
+		// is reported again at the next reading when lost
+		lost := elapsed != 0 && elapsed != duration && rng.Float64() < v.Dropout
+		if lost && event["sealBroken"] == true {
+			sealBroken = false
+		}
+		if !lost {
+			eventJSON, err := json.Marshal(event)
+			if err != nil {
+				return nil, errors.New("Marshal failed for voyage event: " + fmt.Sprint(err))
+			}
+			err = validateArgs(function, []string{string(eventJSON)})
+			if err == nil {
+				err = v.AssetType.checkEventProperties(string(eventJSON))
+			}
+			if err != nil {
+				return nil, errors.New("Generated an event that the contract refuses: " + fmt.Sprint(err))
+			}
+			entries = append(entries, ReplayEntry{
+				Function:  function,
+				Args:      []json.RawMessage{eventJSON},
+				Timestamp: now.Format(time.RFC3339Nano),
+			})
+		}
+		if elapsed == duration {
+			return entries, nil
+		}
+	}
+}
+
+// declares tells whether the events of the asset type may carry the property
+func (v *voyage) declares(name string) bool {
+	for _, p := range v.AssetType.Properties {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// reading returns the value of a profile at the given time and longitude,
+// peaking at 15:00 local solar time, with noise
+func (v *voyage) reading(p voyageProfile, now time.Time, longitude float64, rng *rand.Rand) float64 {
+	hour := float64(now.Hour()) + float64(now.Minute())/60 + longitude/15
+	return p.Base + p.Amplitude*math.Sin(2*math.Pi*(hour-9)/24) + rng.NormFloat64()*v.Noise
+}
+
+// voyagePort returns the coordinates of a named port or of a LAT,LON pair
+func voyagePort(name string) ([2]float64, error) {
+	if port, found := voyagePorts[name]; found {
+		return port, nil
+	}
+	parts := strings.Split(name, ",")
+	if len(parts) == 2 {
+		lat, errLat := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+		lon, errLon := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if errLat == nil && errLon == nil && math.Abs(lat) <= 90 && math.Abs(lon) <= 180 {
+			return [2]float64{lat, lon}, nil
+		}
+	}
+	return [2]float64{}, errors.New("Unknown port " + name + ", expecting a port name or LAT,LON")
+}
+
+// greatCircleDistance returns the distance between two points in nautical
+// miles
+func greatCircleDistance(from [2]float64, to [2]float64) float64 {
+	return centralAngle(from, to) * EARTHRADIUSNM
+}
+
+// greatCirclePoint returns the point at the given fraction of the great
+// circle from one point to the other
+func greatCirclePoint(from [2]float64, to [2]float64, fraction float64) [2]float64 {
+	d := centralAngle(from, to)
+	if d == 0 {
+		return from
+	}
+	lat1, lon1 := radians(from[0]), radians(from[1])
+	lat2, lon2 := radians(to[0]), radians(to[1])
+	a := math.Sin((1-fraction)*d) / math.Sin(d)
+	b := math.Sin(fraction*d) / math.Sin(d)
+	x := a*math.Cos(lat1)*math.Cos(lon1) + b*math.Cos(lat2)*math.Cos(lon2)
+	y := a*math.Cos(lat1)*math.Sin(lon1) + b*math.Cos(lat2)*math.Sin(lon2)
+	z := a*math.Sin(lat1) + b*math.Sin(lat2)
+	return [2]float64{degrees(math.Atan2(z, math.Hypot(x, y))), degrees(math.Atan2(y, x))}
+}
+
+// centralAngle returns the angle between two points seen from the centre of
+// the earth, by the haversine formula
+func centralAngle(from [2]float64, to [2]float64) float64 {
+	lat1, lat2 := radians(from[0]), radians(to[0])
+	dLat, dLon := lat2-lat1, radians(to[1]-from[1])
+	h := math.Pow(math.Sin(dLat/2), 2) + math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(dLon/2), 2)
+	return 2 * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func degrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
+
+func round(value float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(value*scale) / scale
+}
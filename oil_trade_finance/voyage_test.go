@@ -0,0 +1,112 @@
+//go:build simulator
+// +build simulator
+
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+package main
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVoyage(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       string
+		wantStatus int
+		wantOut    []string
+		wantErr    string
+	}{
+		{"crude tank", "-dropout 0", 0, []string{
+			`{"function":"createAsset","args":[{"assetID":"T1","assetType":"CrudeTank","location":{"latitude":51.9496,"longitude":4.1453}`,
+			`"location":{"latitude":1.2644,"longitude":103.84}`,
+		}, ""},
+		{"reefer arguments", "-type ReeferContainer -from Santos -to 10,-20 -carrier alpha -seal-break 0.5 -format args", 0, []string{
+			`{"assetID":"T1","assetType":"ReeferContainer","carrier":"alpha",`,
+			`"maxHumidity":`,
+			`"sealBroken":true`,
+		}, ""},
+		{"vessel", "-type Vessel -asset V1 -from Houston -to Lagos", 0, []string{`{"assetID":"V1","location":`}, ""},
+		{"unknown port", "-to Atlantis", 1, nil, "error: Unknown port Atlantis, expecting a port name or LAT,LON"},
+		{"unknown type", "-type Barrel", 1, nil, "error:"},
+		{"bad speed", "-speed 0", 1, nil, "error: The speed, interval and excursion length must be positive"},
+		{"bad format", "-format csv", 2, nil, "usage: simulator voyage"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+
+			status := simulate(new(SimpleChaincode), append([]string{"voyage"}, strings.Fields(tt.args)...), &stdout, &stderr)
+			if status != tt.wantStatus {
+				t.Fatalf("status %d, expected %d, stderr %s", status, tt.wantStatus, stderr.String())
+			}
+			for _, want := range tt.wantOut {
+				if !strings.Contains(stdout.String(), want) {
+					t.Fatalf("output does not contain %q:\n%s", want, stdout.String())
+				}
+			}
+			if !strings.Contains(stderr.String(), tt.wantErr) {
+				t.Fatalf("stderr does not contain %q:\n%s", tt.wantErr, stderr.String())
+			}
+		})
+	}
+
+	// a vessel reports no readings
+	var stdout, stderr bytes.Buffer
+	simulate(new(SimpleChaincode), []string{"voyage", "-type", "Vessel"}, &stdout, &stderr)
+	if strings.Contains(stdout.String(), "maxTemperature") {
+		t.Fatalf("a vessel reports temperatures:\n%s", stdout.String())
+	}
+}
+
+func TestVoyageReplays(t *testing.T) {
+	var voyages [2]bytes.Buffer
+	for i := range voyages {
+		var stderr bytes.Buffer
+		args := []string{"voyage", "-type", "ReeferContainer", "-seed", "7", "-excursions", "2"}
+		if status := simulate(new(SimpleChaincode), args, &voyages[i], &stderr); status != 0 {
+			t.Fatalf("voyage %d failed: %s", i, stderr.String())
+		}
+	}
+	if !bytes.Equal(voyages[0].Bytes(), voyages[1].Bytes()) {
+		t.Fatal("the same seed gave two different voyages")
+	}
+
+	// every event of the voyage is applied by the contract
+	var stdout, stderr bytes.Buffer
+	logFile := filepath.Join(t.TempDir(), "voyage.jsonl")
+	os.WriteFile(logFile, voyages[0].Bytes(), 0644)
+	if status := simulate(new(SimpleChaincode), []string{"replay", logFile}, &stdout, &stderr); status != 0 {
+		t.Fatalf("replay failed: %s", stderr.String())
+	}
+	if !strings.Contains(stdout.String(), " 0 failed") {
+		t.Fatalf("the contract refused events of the voyage:\n%s", stdout.String())
+	}
+}
+
+func TestGreatCircle(t *testing.T) {
+	rotterdam, singapore := voyagePorts["Rotterdam"], voyagePorts["Singapore"]
+	if d := greatCircleDistance(rotterdam, singapore); math.Abs(d-5701) > 10 {
+		t.Fatalf("Rotterdam to Singapore is %.0f nm, expected about 5701", d)
+	}
+	if p := greatCirclePoint(rotterdam, singapore, 1); math.Abs(p[0]-singapore[0]) > 1e-9 || math.Abs(p[1]-singapore[1]) > 1e-9 {
+		t.Fatalf("the route ends at %v, expected %v", p, singapore)
+	}
+	// halfway along the equator between two meridians
+	if p := greatCirclePoint([2]float64{0, 0}, [2]float64{0, 90}, 0.5); math.Abs(p[0]) > 1e-9 || math.Abs(p[1]-45) > 1e-9 {
+		t.Fatalf("the middle of the route is %v, expected [0 45]", p)
+	}
+}
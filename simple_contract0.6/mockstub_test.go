@@ -0,0 +1,174 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+// In-memory ledger for the tests
+//
+// mockStub stands in for the peer. It keeps the world state in a map and runs
+// every call as a transaction of its own: reads see the state committed by
+// earlier transactions only, and the writes of a transaction are committed
+// when it succeeds and dropped when it fails.
+
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// mockStub is an in-memory shim.ChaincodeStubInterface. The functions the
+// contract does not use are left to the nil interface it embeds and panic.
+type mockStub struct {
+	shim.ChaincodeStubInterface
+
+	t      *testing.T
+	cc     *SimpleChaincode
+	state  map[string][]byte // committed world state
+	writes map[string][]byte // writes of the running transaction, nil deletes
+	args   []string          // function and arguments of the running transaction
+}
+
+// newMockStub returns an empty ledger
+func newMockStub(t *testing.T) *mockStub {
+	return &mockStub{
+		t:     t,
+		cc:    new(SimpleChaincode),
+		state: make(map[string][]byte),
+	}
+}
+
+// newTestContract returns a ledger on which the contract is initialized
+func newTestContract(t *testing.T) *mockStub {
+	m := newMockStub(t)
+	if err := m.init(`{"version":"` + MYVERSION + `"}`); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	return m
+}
+
+/*********************************  transactions ****************************/
+
+// init runs Init with the arguments given
+func (m *mockStub) init(args ...string) error {
+	_, err := m.transact(true, false, "init", args)
+	return err
+}
+
+// invoke runs an invoke and returns its payload
+func (m *mockStub) invoke(function string, args ...string) ([]byte, error) {
+	return m.transact(false, false, function, args)
+}
+
+// query runs a query, which fails the test if it writes to the ledger
+func (m *mockStub) query(function string, args ...string) ([]byte, error) {
+	return m.transact(false, true, function, args)
+}
+
+func (m *mockStub) mustInvoke(function string, args ...string) []byte {
+	m.t.Helper()
+	payload, err := m.invoke(function, args...)
+	if err != nil {
+		m.t.Fatalf("%s %v: %v", function, args, err)
+	}
+	return payload
+}
+
+func (m *mockStub) mustQuery(function string, args ...string) []byte {
+	m.t.Helper()
+	payload, err := m.query(function, args...)
+	if err != nil {
+		m.t.Fatalf("%s %v: %v", function, args, err)
+	}
+	return payload
+}
+
+// transact runs one transaction and commits its writes when it succeeds,
+// readOnly fails the test if a query writes
+func (m *mockStub) transact(init bool, readOnly bool, function string, args []string) ([]byte, error) {
+	m.args = append([]string{function}, args...)
+	m.writes = make(map[string][]byte)
+
+	var resp pb.Response
+	if init {
+		resp = m.cc.Init(m)
+	} else {
+		resp = m.cc.Invoke(m)
+	}
+	if resp.Status != shim.OK {
+		return nil, errors.New(resp.Message)
+	}
+	if readOnly && len(m.writes) > 0 {
+		m.t.Errorf("query %s wrote to the ledger", function)
+	}
+	for key, value := range m.writes {
+		if value == nil {
+			delete(m.state, key)
+			continue
+		}
+		m.state[key] = value
+	}
+	return resp.Payload, nil
+}
+
+/*********************************  shim.ChaincodeStubInterface ****************************/
+
+func (m *mockStub) GetFunctionAndParameters() (string, []string) {
+	if len(m.args) == 0 {
+		return "", []string{}
+	}
+	return m.args[0], m.args[1:]
+}
+
+func (m *mockStub) GetState(key string) ([]byte, error) {
+	return m.state[key], nil
+}
+
+func (m *mockStub) PutState(key string, value []byte) error {
+	if key == "" {
+		return errors.New("key must not be an empty string")
+	}
+	if len(value) == 0 {
+		// the peer treats an empty value as a delete
+		value = nil
+	}
+	m.writes[key] = value
+	return nil
+}
+
+func (m *mockStub) DelState(key string) error {
+	if key == "" {
+		return errors.New("key must not be an empty string")
+	}
+	m.writes[key] = nil
+	return nil
+}
+
+/*********************************  assertions ****************************/
+
+// wantError fails the test unless err is an error containing want, or nil
+// when want is empty
+func wantError(t *testing.T, err error, want string) {
+	t.Helper()
+	if want == "" {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return
+	}
+	if err == nil {
+		t.Fatalf("expected an error containing %q, got none", want)
+	}
+	if !strings.Contains(err.Error(), want) {
+		t.Fatalf("expected an error containing %q, got %q", want, err.Error())
+	}
+}
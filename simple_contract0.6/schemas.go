@@ -87,7 +87,7 @@ var schemas = `
             "type": "object"
         },
         "init": {
-            "description": "Initializes the contract when started, either by deployment or by peer restart.",
+            "description": "Initializes the contract when it is instantiated or upgraded.",
             "properties": {
                 "args": {
                     "description": "args are JSON encoded strings",
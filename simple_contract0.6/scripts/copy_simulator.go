@@ -0,0 +1,52 @@
+//go:build ignore
+// +build ignore
+
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+// Simulator copy
+//
+// copy_simulator writes simulator.go for the contract in the current
+// directory from that of the oil trade finance contract, which runs any
+// shim.Chaincode, so that both contracts share one simulator. Run it from the
+// contract directory with
+//     go generate
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+)
+
+// simulatorSource is read relative to the contract directory
+const simulatorSource = "../oil_trade_finance/simulator.go"
+
+// copyNote is the line of the source that names the copy, it says that the
+// copy is generated instead
+const copyNote = "// simple_contract0.6/simulator.go is generated from this file by go generate.\n"
+
+const generatedNote = "// Code generated by scripts/copy_simulator.go from oil_trade_finance/simulator.go. DO NOT EDIT.\n"
+
+func main() {
+	source, err := ioutil.ReadFile(simulatorSource)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if bytes.Count(source, []byte(copyNote)) != 1 {
+		log.Fatalf("%s does not name its copy as expected", simulatorSource)
+	}
+	copied := bytes.Replace(source, []byte(copyNote), []byte(generatedNote), 1)
+	err = ioutil.WriteFile("simulator.go", copied, 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
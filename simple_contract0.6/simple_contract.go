@@ -19,6 +19,8 @@ Sumabala Nair - Partial updates added May 2016
 // This is a simple contract that creates a CRUD interface to
 // create, read, update and delete an asset
 
+//go:generate go run scripts/copy_simulator.go
+
 package main
 
 import (
@@ -28,7 +30,8 @@ import (
 	"reflect"
 	"strings"
 
-	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
 )
 
 // SimpleChaincode example simple Chaincode implementation
@@ -70,8 +73,14 @@ var contractState = ContractState{MYVERSION}
 // deploy callback mode
 // ************************************
 
-// Init is called during deploy
-func (t *SimpleChaincode) Init(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
+// Init is called when the contract is instantiated or upgraded
+func (t *SimpleChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	_, args := stub.GetFunctionAndParameters()
+	return response(t.initContract(stub, args))
+}
+
+// initContract checks the version argument of Init and stores the contract state
+func (t *SimpleChaincode) initContract(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
 	var stateArg ContractState
 	var err error
 	if len(args) != 1 {
@@ -99,8 +108,26 @@ func (t *SimpleChaincode) Init(stub shim.ChaincodeStubInterface, function string
 // deploy and invoke callback mode
 // ************************************
 
-// Invoke is called when an invoke message is received
-func (t *SimpleChaincode) Invoke(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
+// queryFunctions only read the ledger
+var queryFunctions = map[string]bool{
+	"readAsset":            true,
+	"readAssetObjectModel": true,
+	"readAssetSamples":     true,
+	"readAssetSchemas":     true,
+}
+
+// Invoke is called for every transaction, the first argument names the
+// function. Queries come through Invoke too and are told apart by name.
+func (t *SimpleChaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	function, args := stub.GetFunctionAndParameters()
+	if queryFunctions[function] {
+		return response(t.query(stub, function, args))
+	}
+	return response(t.invoke(stub, function, args))
+}
+
+// invoke runs a function that writes to the ledger
+func (t *SimpleChaincode) invoke(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
 	// Handle different functions
 	if function == "createAsset" {
 		// create assetID
@@ -116,11 +143,11 @@ func (t *SimpleChaincode) Invoke(stub shim.ChaincodeStubInterface, function stri
 }
 
 // ************************************
-// query callback mode
+// query mode
 // ************************************
 
-// Query is called when a query message is received
-func (t *SimpleChaincode) Query(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
+// query runs a function that reads the ledger
+func (t *SimpleChaincode) query(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
 	// Handle different functions
 	if function == "readAsset" {
 		// gets the state for an assetID as a JSON struct
@@ -137,13 +164,12 @@ func (t *SimpleChaincode) Query(stub shim.ChaincodeStubInterface, function strin
 	return nil, errors.New("Received unknown invocation: " + function)
 }
 
-/**********main implementation *************/
-
-func main() {
-	err := shim.Start(new(SimpleChaincode))
+// response turns the outcome of a function into a peer response
+func response(payload []byte, err error) pb.Response {
 	if err != nil {
-		fmt.Printf("Error starting Simple Chaincode: %s", err)
+		return shim.Error(err.Error())
 	}
+	return shim.Success(payload)
 }
 
 /*****************ASSET CRUD INTERFACE starts here************/
@@ -0,0 +1,215 @@
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestInit(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"version", []string{`{"version":"1.0"}`}, ""},
+		{"other version", []string{`{"version":"2.0"}`}, "Contract version 1.0 must match version argument: 2.0"},
+		{"not JSON", []string{`1.0`}, "Version argument unmarshal failed"},
+		{"no arguments", nil, "init expects one argument, a JSON string with tagged version string"},
+		{"two arguments", []string{`{"version":"1.0"}`, `{}`}, "init expects one argument"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMockStub(t)
+			err := m.init(tt.args...)
+			wantError(t, err, tt.wantErr)
+			if err != nil {
+				if len(m.state) != 0 {
+					t.Fatal("a failed init wrote to the ledger")
+				}
+				return
+			}
+			if string(m.state[CONTRACTSTATEKEY]) != `{"version":"1.0"}` {
+				t.Fatalf("unexpected contract state %s", m.state[CONTRACTSTATEKEY])
+			}
+		})
+	}
+}
+
+func TestCreateAndUpdateAsset(t *testing.T) {
+	steps := []struct {
+		function string
+		args     string
+		wantErr  string
+		want     string
+	}{
+		{"createAsset", `{"assetID":" A1 ","temperature":20}`, "", `{"assetID":"A1","temperature":20}`},
+		{"updateAsset", `{"assetID":"A1","carrier":"alpha"}`, "", `{"assetID":"A1","temperature":20,"carrier":"alpha"}`},
+		{"updateAsset", `{"assetID":"A1","location":{"latitude":1.5,"longitude":2.5},"temperature":-3}`, "",
+			`{"assetID":"A1","location":{"latitude":1.5,"longitude":2.5},"temperature":-3,"carrier":"alpha"}`},
+		// a null leaves the stored value alone, as a missing property does
+		{"updateAsset", `{"assetID":"A1","carrier":null}`, "",
+			`{"assetID":"A1","location":{"latitude":1.5,"longitude":2.5},"temperature":-3,"carrier":"alpha"}`},
+		// the location is replaced as a whole
+		{"updateAsset", `{"assetID":"A1","location":{"latitude":9}}`, "", `{"assetID":"A1","location":{"latitude":9},"temperature":-3,"carrier":"alpha"}`},
+		{"updateAsset", `{"assetID":"A1","temperature":"cold"}`, "Unable to unmarshal input JSON data", ""},
+		{"createAsset", `{"temperature":20}`, "Asset id is mandatory in the input JSON data", ""},
+		{"updateAsset", `{"assetID":" "}`, "AssetID not passed", ""},
+		// an update of an unknown asset creates it
+		{"updateAsset", `{"assetID":"A2"}`, "", `{"assetID":"A2"}`},
+		{"moveAsset", `{"assetID":"A1"}`, "Received unknown invocation: moveAsset", ""},
+	}
+
+	m := newTestContract(t)
+	for i, step := range steps {
+		payload, err := m.invoke(step.function, step.args)
+		wantError(t, err, step.wantErr)
+		if err != nil {
+			continue
+		}
+		if payload != nil {
+			t.Fatalf("step %d: unexpected payload %s", i, payload)
+		}
+		var state AssetState
+		json.Unmarshal([]byte(step.want), &state)
+		want, _ := json.Marshal(state)
+		if got := m.mustQuery("readAsset", step.args); string(got) != string(want) {
+			t.Fatalf("step %d: state %s, expected %s", i, got, want)
+		}
+	}
+}
+
+func TestDeleteAsset(t *testing.T) {
+	m := newTestContract(t)
+	m.mustInvoke("createAsset", `{"assetID":"A1","temperature":20}`)
+	m.mustInvoke("deleteAsset", `{"assetID":"A1"}`)
+	if _, found := m.state["A1"]; found {
+		t.Fatal("A1 is still on the ledger")
+	}
+	_, err := m.query("readAsset", `{"assetID":"A1"}`)
+	wantError(t, err, "Unable to get asset state from ledger")
+	// deleting an asset that does not exist is not an error
+	m.mustInvoke("deleteAsset", `{"assetID":"A1"}`)
+	_, err = m.invoke("deleteAsset")
+	wantError(t, err, "Incorrect number of arguments. Expecting a JSON strings with mandatory assetID")
+}
+
+func TestReadAsset(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"stored", []string{`{"assetID":"A1"}`}, ""},
+		{"unknown", []string{`{"assetID":"A9"}`}, "Unable to get asset state from ledger"},
+		{"no asset ID", []string{`{}`}, "Asset does not exist!"},
+		{"no arguments", nil, "Asset does not exist!"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestContract(t)
+			m.mustInvoke("createAsset", `{"assetID":"A1","temperature":20}`)
+			payload, err := m.query("readAsset", tt.args...)
+			wantError(t, err, tt.wantErr)
+			if err == nil && string(payload) != `{"assetID":"A1","temperature":20}` {
+				t.Fatalf("unexpected payload %s", payload)
+			}
+		})
+	}
+}
+
+func TestStaticQueries(t *testing.T) {
+	tests := []struct {
+		function string
+		want     string
+	}{
+		{"readAssetObjectModel", `{}`},
+		{"readAssetSamples", samples},
+		{"readAssetSchemas", schemas},
+	}
+	m := newMockStub(t)
+	for _, tt := range tests {
+		payload := m.mustQuery(tt.function)
+		if string(payload) != tt.want {
+			t.Errorf("%s returned %.40s..., expected %.40s...", tt.function, payload, tt.want)
+		}
+		if !json.Valid(payload) {
+			t.Errorf("%s does not return valid JSON", tt.function)
+		}
+	}
+	_, err := m.query("readAssetHistory", `{"assetID":"A1"}`)
+	wantError(t, err, "Received unknown invocation: readAssetHistory")
+}
+
+func TestValidateInput(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantID  string
+		wantErr string
+	}{
+		{"asset ID", []string{`{"assetID":"A1"}`}, "A1", ""},
+		{"trimmed", []string{`{"assetID":"  A1\t"}`}, "A1", ""},
+		{"with state", []string{`{"assetID":"A1","temperature":4}`}, "A1", ""},
+		{"blank", []string{`{"assetID":"   "}`}, "", "AssetID not passed"},
+		{"missing", []string{`{"temperature":4}`}, "", "Asset id is mandatory in the input JSON data"},
+		{"null", []string{`{"assetID":null}`}, "", "Asset id is mandatory in the input JSON data"},
+		{"not a string", []string{`{"assetID":7}`}, "", "Unable to unmarshal input JSON data"},
+		{"not JSON", []string{`A1`}, "", "Unable to unmarshal input JSON data"},
+		{"no arguments", nil, "", "Incorrect number of arguments"},
+		{"two arguments", []string{`{"assetID":"A1"}`, `{"assetID":"A2"}`}, "", "Incorrect number of arguments"},
+	}
+	cc := new(SimpleChaincode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := cc.validateInput(tt.args)
+			wantError(t, err, tt.wantErr)
+			if tt.wantErr == "" && *state.AssetID != tt.wantID {
+				t.Fatalf("asset ID %q, expected %q", *state.AssetID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestMergePartialState(t *testing.T) {
+	tests := []struct {
+		name     string
+		oldState string
+		newState string
+		want     string
+	}{
+		{"new property", `{"assetID":"A1"}`, `{"temperature":4}`, `{"assetID":"A1","temperature":4}`},
+		{"replaced", `{"assetID":"A1","temperature":4}`, `{"temperature":5}`, `{"assetID":"A1","temperature":5}`},
+		{"zero value replaces", `{"assetID":"A1","temperature":4}`, `{"temperature":0}`, `{"assetID":"A1","temperature":0}`},
+		{"null keeps", `{"assetID":"A1","carrier":"alpha"}`, `{"carrier":null}`, `{"assetID":"A1","carrier":"alpha"}`},
+		{"struct replaced whole", `{"location":{"latitude":1,"longitude":2}}`, `{"location":{"longitude":3}}`, `{"location":{"longitude":3}}`},
+		{"empty", `{"assetID":"A1","carrier":"alpha"}`, `{}`, `{"assetID":"A1","carrier":"alpha"}`},
+	}
+	cc := new(SimpleChaincode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var oldState, newState, want AssetState
+			json.Unmarshal([]byte(tt.oldState), &oldState)
+			json.Unmarshal([]byte(tt.newState), &newState)
+			json.Unmarshal([]byte(tt.want), &want)
+			got, err := cc.mergePartialState(oldState, newState)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				gotJSON, _ := json.Marshal(got)
+				t.Fatalf("merged into %s, expected %s", gotJSON, tt.want)
+			}
+		})
+	}
+}
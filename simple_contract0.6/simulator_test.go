@@ -0,0 +1,73 @@
+//go:build simulator
+// +build simulator
+
+/*******************************************************************************
+Copyright (c) 2016 IBM Corporation and other Contributors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and limitations under the License.
+******************************************************************************/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSimulate(t *testing.T) {
+	ledgerFile := filepath.Join(t.TempDir(), "ledger.json")
+	steps := []struct {
+		args       string
+		wantStatus int
+		wantOut    []string
+		wantErr    string
+	}{
+		{`init {"version":"1.0"}`, 0, []string{"init tx000001 at", `+ ContractStateKey {"version":"1.0"}`}, ""},
+		{`-time 2017-03-20T08:00:00Z invoke createAsset {"assetID":"T1","temperature":20}`, 0,
+			[]string{"invoke tx000002 at 2017-03-20T08:00:00Z", `+ T1 {"assetID":"T1","temperature":20}`}, ""},
+		{`invoke updateAsset {"assetID":"T1","carrier":"alpha"}`, 0, []string{"~ T1", `before: {"assetID":"T1","temperature":20}`, `"carrier":"alpha"`}, ""},
+		{`query readAsset {"assetID":"T1"}`, 0, []string{"query tx000004", "payload:", `"carrier": "alpha"`, "no state changes"}, ""},
+		{`invoke deleteAsset {"assetID":"T1"}`, 0, []string{"- T1"}, ""},
+		{`query readAsset {"assetID":"T1"}`, 1, nil, "error: Unable to get asset state from ledger"},
+		{`invoke moveAsset {"assetID":"T1"}`, 1, nil, "error: Received unknown invocation: moveAsset"},
+		{`query`, 2, nil, "usage: simulator"},
+	}
+	for i, step := range steps {
+		var stdout, stderr bytes.Buffer
+		args := append([]string{"-ledger", ledgerFile}, strings.Split(step.args, " ")...)
+		status := simulate(new(SimpleChaincode), args, &stdout, &stderr)
+		if status != step.wantStatus {
+			t.Fatalf("step %d: status %d, expected %d, stderr %s", i, status, step.wantStatus, stderr.String())
+		}
+		for _, want := range step.wantOut {
+			if !strings.Contains(stdout.String(), want) {
+				t.Fatalf("step %d: output does not contain %q:\n%s", i, want, stdout.String())
+			}
+		}
+		if !strings.Contains(stderr.String(), step.wantErr) {
+			t.Fatalf("step %d: stderr does not contain %q:\n%s", i, step.wantErr, stderr.String())
+		}
+	}
+
+	// the failed calls and the queries did not commit
+	var ledger simulatorLedger
+	ledgerBytes, err := os.ReadFile(ledgerFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	json.Unmarshal(ledgerBytes, &ledger)
+	if ledger.TxCount != 4 {
+		t.Fatalf("%d transactions committed, expected 4", ledger.TxCount)
+	}
+	if _, found := ledger.State["T1"]; found {
+		t.Fatal("the deleted asset is still in the ledger file")
+	}
+}